@@ -0,0 +1,120 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"html"
+	"net/url"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// How many days of announcements the digest shows by default
+const defaultAnnouncementDays = 7
+
+type canvas_announcement struct {
+	Id           int    `json:"id"`
+	Title        string `json:"title"`
+	Message      string `json:"message"` // HTML
+	Posted_At    string `json:"posted_at"`
+	Html_Url     string `json:"html_url"`
+	Context_Code string `json:"context_code"` // ex. "course_1461901"
+	Author       struct {
+		Display_Name string `json:"display_name"`
+	} `json:"author"`
+}
+
+// Announcement is an announcement ready for the page, with its message as plain text
+type Announcement struct {
+	Title    string
+	Text     string
+	Author   string
+	PostedAt time.Time
+	URL      string
+}
+
+// Helper function to read how many days back the digest goes, 0 turns it off
+func announcementDays() int {
+	return int(GetEnvVarInt64("ANNOUNCEMENT_DAYS", defaultAnnouncementDays, 0, 90, "", "announcement-days"))
+}
+
+// GetAnnouncements returns the announcements posted in the given contexts between start and end
+func (c *CanvasClient) GetAnnouncements(ctx context.Context, contextCodes []string, start, end time.Time) ([]canvas_announcement, error) {
+	query := url.Values{}
+	query.Set("start_date", start.UTC().Format(time.RFC3339))
+	query.Set("end_date", end.UTC().Format(time.RFC3339))
+	query.Set("active_only", "true") // skip delayed posts that aren't visible yet
+
+	result, err := getContextPages[canvas_announcement](ctx, c, "/announcements", query, contextCodes)
+	if err != nil {
+		return nil, err
+	}
+	fmt.Printf("Found %d announcements\n", len(result))
+	return result, nil
+}
+
+// ToAnnouncement converts the HTML message to plain text
+func (a canvas_announcement) ToAnnouncement() Announcement {
+	return Announcement{
+		Title:    a.Title,
+		Text:     htmlToText(a.Message),
+		Author:   a.Author.Display_Name,
+		PostedAt: parseCanvasTime(a.Posted_At),
+		URL:      a.Html_Url,
+	}
+}
+
+// AddAnnouncements fetches the announcements of the last days before now, once
+// per Canvas instance, and adds them to the matching course groups
+func AddAnnouncements(ctx context.Context, groups []CourseItems, now time.Time) {
+	days := announcementDays()
+	if days == 0 {
+		return
+	}
+	start := now.AddDate(0, 0, -days)
+
+	forEachClient(groups, func(client *CanvasClient, courses []Course, indexes []int) {
+		announcements, err := client.GetAnnouncements(ctx, courseContextCodes(courses), start, now)
+		for _, i := range indexes {
+			if err != nil {
+				groups[i].Errors = append(groups[i].Errors, FetchError{What: "announcements", Err: err})
+				continue
+			}
+			code := fmt.Sprintf("course_%d", groups[i].Course.CourseID)
+			for _, announcement := range announcements {
+				if announcement.Context_Code == code {
+					groups[i].Announcements = append(groups[i].Announcements, announcement.ToAnnouncement())
+				}
+			}
+		}
+	})
+}
+
+var (
+	htmlParagraphEnd = regexp.MustCompile(`(?i)</(p|div|h[1-6]|ul|ol|table|blockquote|pre)>`)
+	htmlLineBreak    = regexp.MustCompile(`(?i)<br\s*/?>|</tr>`)
+	htmlListItem     = regexp.MustCompile(`(?i)<li[^>]*>`)
+	htmlTags         = regexp.MustCompile(`(?s)<[^>]*>`)
+	htmlHidden       = regexp.MustCompile(`(?is)<(script|style)[^>]*>.*?</(script|style)>`)
+	blankLines       = regexp.MustCompile(`\n{3,}`)
+)
+
+// Helper function to turn an announcement's HTML into plain text, keeping
+// paragraphs and list items on their own lines
+func htmlToText(s string) string {
+	s = htmlHidden.ReplaceAllString(s, "")
+	s = htmlListItem.ReplaceAllString(s, "\n- ")
+	s = htmlParagraphEnd.ReplaceAllString(s, "\n\n")
+	s = htmlLineBreak.ReplaceAllString(s, "\n")
+	s = htmlTags.ReplaceAllString(s, "")
+	s = html.UnescapeString(s)
+
+	lines := strings.Split(s, "\n")
+	for i, line := range lines {
+		lines[i] = strings.Join(strings.Fields(line), " ")
+	}
+	s = strings.Join(lines, "\n")
+	s = blankLines.ReplaceAllString(s, "\n\n")
+	return strings.TrimSpace(s)
+}
@@ -1,32 +1,103 @@
 package main
 
 import (
-	"bytes"
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
-	"io/ioutil"
-	"log"
-	"net/http"
-	"os"
+	"time"
 )
 
 type assignment_due struct {
-	Due_At                    string `json:"due_at"`
-	Name                      string `json:"name"`
-	Id                        int    `json:"id"`
-	Has_Submitted_Submissions bool   `json:"has_submitted_submissions"`
-	Is_Quiz_Assignment        bool   `json:"is_quiz_assignment"`
-	Require_Lockdown_Browser  bool   `json:"require_lockdown_browser"`
-	Locked_For_User           bool   `json:locked_for_user`
+	Due_At                    string   `json:"due_at"`
+	Name                      string   `json:"name"`
+	Id                        int      `json:"id"`
+	Course_Id                 int      `json:"course_id"`
+	Html_Url                  string   `json:"html_url"`
+	Points_Possible           float64  `json:"points_possible"`
+	Lock_At                   string   `json:"lock_at"`
+	Unlock_At                 string   `json:"unlock_at"`
+	Todo_Date                 string   `json:"todo_date"`
+	Submission_Types          []string `json:"submission_types"`
+	Published                 bool     `json:"published"`
+	Workflow_State            string   `json:"workflow_state"`
+	Has_Submitted_Submissions bool     `json:"has_submitted_submissions"`
+	Is_Quiz_Assignment        bool     `json:"is_quiz_assignment"`
+	Is_Quiz_Lti_Assignment    bool     `json:"is_quiz_lti_assignment"` // New Quizzes
+	Require_Lockdown_Browser  bool     `json:"require_lockdown_browser"`
+	Locked_For_User           bool     `json:"locked_for_user"`
+	Assignment_Group_Id       int      `json:"assignment_group_id"`
+	Omit_From_Final_Grade     bool     `json:"omit_from_final_grade"`
+	Source                    string   `json:"-"` // name of the Canvas instance it came from
+	// My own submission, only sent when requested with include[]=submission
+	Submission *canvas_submission `json:"submission,omitempty"`
+	// Section and student specific dates, sent with include[]=all_dates / include[]=overrides
+	All_Dates []assignment_date     `json:"all_dates,omitempty"`
+	Overrides []assignment_override `json:"overrides,omitempty"`
+}
+
+type canvas_submission struct {
+	Workflow_State string   `json:"workflow_state"`
+	Submitted_At   string   `json:"submitted_at"`
+	Late           bool     `json:"late"`
+	Missing        bool     `json:"missing"`
+	Score          *float64 `json:"score"`
 }
 
 type discussion_due struct {
-	Due_At      string         `json:"due_at"`
-	Title       string         `json:"title"`
-	Id          int            `json:"id"`
-	Description string         `json:"description"`
-	Assignment  assignment_due `json:"assignment"`
-	Created_At  string         `json:created_at"`
+	Due_At          string         `json:"due_at"`
+	Title           string         `json:"title"`
+	Id              int            `json:"id"`
+	Description     string         `json:"description"`
+	Html_Url        string         `json:"html_url"`
+	Lock_At         string         `json:"lock_at"`
+	Todo_Date       string         `json:"todo_date"`
+	Published       bool           `json:"published"`
+	Workflow_State  string         `json:"workflow_state"`
+	Locked_For_User bool           `json:"locked_for_user"`
+	Assignment_Id   int            `json:"assignment_id"`
+	Assignment      assignment_due `json:"assignment"`
+	Created_At      string         `json:"created_at"`
+	Source          string         `json:"-"` // name of the Canvas instance it came from
+}
+
+// Check if I have actually turned the assignment in. has_submitted_submissions
+// only says that someone in the course has, and "graded" alone isn't enough
+// either, Canvas also grades missing work to 0 without a submission.
+func (a assignment_due) Submitted() bool {
+	if a.Submission == nil || a.Submission.Missing {
+		return false
+	}
+	if a.Submission.Submitted_At != "" {
+		return true
+	}
+	switch a.Submission.Workflow_State {
+	case "submitted", "pending_review":
+		return true
+	}
+	return false
+}
+
+// Helper function to copy my submission and my resolved dates onto graded
+// discussions. The discussion topics endpoint can't include either, but the
+// assignments endpoint lists graded discussions as assignments with the same id.
+func attachDiscussionAssignments(discussions []discussion_due, assignments []assignment_due) {
+	byId := make(map[int]assignment_due)
+	for _, assignment := range assignments {
+		byId[assignment.Id] = assignment
+	}
+	for i := range discussions {
+		assignment, ok := byId[discussions[i].Assignment.Id]
+		if !ok {
+			continue
+		}
+		if discussions[i].Assignment.Submission == nil {
+			discussions[i].Assignment.Submission = assignment.Submission
+		}
+		discussions[i].Assignment.Due_At = assignment.Due_At
+		discussions[i].Assignment.Unlock_At = assignment.Unlock_At
+		discussions[i].Assignment.Lock_At = assignment.Lock_At
+	}
 }
 
 type external_tools struct {
@@ -46,164 +117,92 @@ type module_info struct {
 	State     string `json:"state"`
 }
 
-func GetAllAssignments() []assignment_due {
-	canvasApiKey := GetEnvVar("CANVAS_API")
-	//url := "https://webcourses.ucf.edu/api/v1/courses/1461901/assignments"
-	//url := "https://webcourses.ucf.edu/api/v1/courses/1461901/assignments/8554277"
-	url := "https://webcourses.ucf.edu/api/v1/courses/1465496/assignments"
-
-	req, err := http.NewRequest("GET", url, nil)
-	if err != nil {
-		fmt.Println("error creating request:", err)
-		return []assignment_due{}
-	}
-
-	req.Header.Add("Authorization", "Bearer "+canvasApiKey)
-
-	client := &http.Client{}
-	resp, err := client.Do(req)
-	if err != nil {
-		fmt.Println("Error sending request: ", err)
-		return []assignment_due{}
-	}
-	defer resp.Body.Close()
-
-	body, err := ioutil.ReadAll(resp.Body)
-	if err != nil {
-		fmt.Println("Error reading response body:", err)
-		return []assignment_due{}
-	}
-
-	var prettyJSON1 bytes.Buffer
-	error := json.Indent(&prettyJSON1, body, "", "\t")
-	if error != nil {
-		log.Println("JSON parse error: ", error)
-
-		return []assignment_due{}
-	}
-
-	f, err := os.OpenFile("./text_response.txt", os.O_RDWR, 0644)
-	if err != nil {
-		log.Fatal(err)
+// GetAllAssignments returns the assignments of every enabled course in the courses config
+func GetAllAssignments(ctx context.Context) ([]assignment_due, error) {
+	var result []assignment_due
+	for _, course := range LoadCourses(ctx, RunTime()) {
+		// Only the module walk needs the quizzes, to map quiz items to their assignments
+		var quizzes []canvas_quiz
+		if course.Strategy == StrategyModules {
+			found, err := GetQuizzesForCourse(ctx, course)
+			if err != nil {
+				return result, fmt.Errorf("%s: %w", course.Name, err)
+			}
+			quizzes = found
+		}
+		assignments, err := GetAssignmentsForCourse(ctx, course, quizzes)
+		if err != nil {
+			return result, fmt.Errorf("%s: %w", course.Name, err)
+		}
+		result = append(result, assignments...)
 	}
+	return result, nil
+}
 
-	n3, err := f.WriteString(prettyJSON1.String())
-	check(err)
-	fmt.Printf("wrote %d bytes\n", n3)
-
+// GetAssignmentsForCourse fetches a course's assignments using its configured strategy
+// and tags each one with the course's Canvas source. quizzes are the course's
+// quizzes, which the module strategy maps quiz items through.
+func GetAssignmentsForCourse(ctx context.Context, course Course, quizzes []canvas_quiz) ([]assignment_due, error) {
 	var result []assignment_due
-	json.Unmarshal(body, &result)
-	if err != nil {
-		fmt.Println("Error unmarshaling JSON: ", err)
-		return []assignment_due{}
+	var err error
+	if course.Strategy == StrategyModules {
+		result, err = course.Client.GetAllAssignmentsByModule(ctx, course.CourseID, quizzes)
+	} else {
+		result, err = course.Client.GetAllAssignmentsByCourse(ctx, course.CourseID)
 	}
-	fmt.Printf("Found %d assignments\n", len(result))
-	fmt.Println("Success from GetAll")
-	for _, assignment := range result {
-		fmt.Println("name: " + assignment.Name)
-		fmt.Println("due at: " + assignment.Due_At)
+	if err != nil {
+		return nil, err
 	}
-	resultsJSON, _ := json.Marshal(result)
-	var prettyJSON2 bytes.Buffer
-	error2 := json.Indent(&prettyJSON2, resultsJSON, "", "\t")
-	if error2 != nil {
-		log.Println("JSON parse error: ", error)
 
-		return []assignment_due{}
+	// Sections and extensions get their own dates, only look me up when some are there
+	if hasOverrideDates(result) {
+		me := course.Client.dateContext(ctx, course.CourseID)
+		for i := range result {
+			result[i].ResolveDates(me)
+		}
 	}
 
-	f2, err := os.OpenFile("./os_results.txt", os.O_RDWR, 0644)
-	if err != nil {
-		log.Fatal(err)
+	for i := range result {
+		result[i].Source = course.Source
 	}
-
-	n2, err := f2.WriteString(prettyJSON2.String())
-	check(err)
-	fmt.Printf("wrote %d bytes\n", n2)
-	//fmt.Println(string(body))
-	return result
+	return result, nil
 }
 
-func check(e error) {
-	if e != nil {
-		panic(e)
+// Helper function to check if any assignment has a date besides the base one
+func hasOverrideDates(assignments []assignment_due) bool {
+	for _, assignment := range assignments {
+		for _, date := range assignment.All_Dates {
+			if !date.Base {
+				return true
+			}
+		}
 	}
+	return false
 }
 
-func GetDiscussionPost() []discussion_due {
-	canvasApiKey := GetEnvVar("CANVAS_API")
-	url := "https://webcourses.ucf.edu/api/v1/courses/1461901/discussion_topics"
-
-	req, err := http.NewRequest("GET", url, nil)
+// GetDiscussionsForCourse fetches a course's discussions and tags each one with the course's Canvas source
+func GetDiscussionsForCourse(ctx context.Context, course Course) ([]discussion_due, error) {
+	result, err := course.Client.GetDiscussionPostByCourse(ctx, course.CourseID)
 	if err != nil {
-		fmt.Println("error creating request:", err)
-		return []discussion_due{}
+		return nil, err
 	}
-
-	req.Header.Add("Authorization", "Bearer "+canvasApiKey)
-
-	client := &http.Client{}
-	resp, err := client.Do(req)
-	if err != nil {
-		fmt.Println("Error sending request: ", err)
-		return []discussion_due{}
-	}
-	defer resp.Body.Close()
-
-	body, err := ioutil.ReadAll(resp.Body)
-	if err != nil {
-		fmt.Println("Error reading response body:", err)
-		return []discussion_due{}
+	for i := range result {
+		result[i].Source = course.Source
 	}
-	//fmt.Println("Raw response body:", string(body))
-	var prettyJSON1 bytes.Buffer
-	error := json.Indent(&prettyJSON1, body, "", "\t")
-	if error != nil {
-		log.Println("JSON parse error: ", error)
-
-		return []discussion_due{}
-	}
-
-	f, err := os.OpenFile("./discussion_response.txt", os.O_RDWR, 0644)
-	if err != nil {
-		log.Fatal(err)
-	}
-
-	n3, err := f.WriteString(prettyJSON1.String())
-	check(err)
-	fmt.Printf("wrote %d bytes\n", n3)
+	return result, nil
+}
 
+// GetDiscussionPost returns the discussions of every enabled course in the courses config
+func GetDiscussionPost(ctx context.Context) ([]discussion_due, error) {
 	var result []discussion_due
-	json.Unmarshal(body, &result)
-	if err != nil {
-		fmt.Println("Error unmarshaling JSON: ", err)
-		return []discussion_due{}
-	}
-	fmt.Printf("Found %d discussions\n", len(result))
-	fmt.Println("Success from GetAll")
-	for _, discussion := range result {
-		fmt.Println("name: " + discussion.Title)
-		fmt.Println("due at: " + discussion.Assignment.Due_At)
-	}
-	resultsJSON, _ := json.Marshal(result)
-	var prettyJSON2 bytes.Buffer
-	error2 := json.Indent(&prettyJSON2, resultsJSON, "", "\t")
-	if error2 != nil {
-		log.Println("JSON parse error: ", error)
-
-		return []discussion_due{}
-	}
-
-	f2, err := os.OpenFile("./disucssion_results.txt", os.O_RDWR, 0644)
-	if err != nil {
-		log.Fatal(err)
+	for _, course := range LoadCourses(ctx, RunTime()) {
+		discussions, err := GetDiscussionsForCourse(ctx, course)
+		if err != nil {
+			return result, fmt.Errorf("%s: %w", course.Name, err)
+		}
+		result = append(result, discussions...)
 	}
-
-	n2, err := f2.WriteString(prettyJSON2.String())
-	check(err)
-	fmt.Printf("wrote %d bytes\n", n2)
-	//fmt.Println(string(body))
-	return result
+	return result, nil
 }
 
 /*
@@ -211,163 +210,55 @@ GetExternalTools() []external_tools {
 
 }
 */
-func GetAssignmentById(id int) assignment_due {
-	canvasApiKey := GetEnvVar("CANVAS_API")
-	url := fmt.Sprintf("https://webcourses.ucf.edu/api/v1/courses/1461901/assignments/%d", id)
-
-	req, err := http.NewRequest("GET", url, nil)
-	if err != nil {
-		fmt.Println("Error creating request:", err)
-		return assignment_due{}
-	}
-
-	req.Header.Add("Authorization", "Bearer "+canvasApiKey)
-
-	client := &http.Client{}
-	resp, err := client.Do(req)
-	if err != nil {
-		fmt.Println("Error sending request: ", err)
-		return assignment_due{}
-	}
-	defer resp.Body.Close()
+func (c *CanvasClient) GetAssignmentById(ctx context.Context, course int, id int) (assignment_due, error) {
+	url := c.url("/courses/%d/assignments/%d?include[]=submission&include[]=all_dates&include[]=overrides", course, id)
 
-	body, err := ioutil.ReadAll(resp.Body)
+	body, err := c.Get(ctx, url)
 	if err != nil {
-		fmt.Println("Error reading response body:", err)
-		return assignment_due{}
-	}
-
-	if resp.StatusCode != http.StatusOK {
-		fmt.Printf("Error: received status code %d for assignment ID %d\n", resp.StatusCode, id)
-		return assignment_due{}
+		return assignment_due{}, err
 	}
 
 	// Unmarshal into a single assignment_due object
 	var result assignment_due
-	err = json.Unmarshal(body, &result)
-	if err != nil {
-		fmt.Println("Error unmarshaling JSON for assignment ID:", id, err)
-		return assignment_due{}
+	if err := json.Unmarshal(body, &result); err != nil {
+		return assignment_due{}, &CanvasError{Kind: ErrCanvasDecode, URL: url, Err: err}
 	}
 
 	if result.Name == "" {
 		fmt.Printf("Warning: Empty assignment retrieved for ID %d\n", id)
 	}
 
-	return result
+	return result, nil
 }
 
-func GetModuleAssignments(module_id int) []module_assignment {
-	canvasApiKey := GetEnvVar("CANVAS_API")
-	url := fmt.Sprintf("https://webcourses.ucf.edu/api/v1/courses/1461901/modules/%d/items", module_id)
-
-	req, err := http.NewRequest("GET", url, nil)
-	if err != nil {
-		fmt.Println("error creating request:", err)
-		return nil
-	}
-
-	req.Header.Add("Authorization", "Bearer "+canvasApiKey)
-
-	client := &http.Client{}
-	resp, err := client.Do(req)
-	if err != nil {
-		fmt.Println("Error sending request: ", err)
-		return nil
-	}
-	defer resp.Body.Close()
+func (c *CanvasClient) GetModuleAssignments(ctx context.Context, course int, module_id int) ([]module_assignment, error) {
+	url := c.url("/courses/%d/modules/%d/items", course, module_id)
 
-	body, err := ioutil.ReadAll(resp.Body)
+	body, err := c.GetPages(ctx, url)
 	if err != nil {
-		fmt.Println("Error reading response body:", err)
-		return nil
-	}
-
-	// Pretty print the JSON
-	var prettyJSON1 bytes.Buffer
-	if err := json.Indent(&prettyJSON1, body, "", "\t"); err != nil {
-		log.Println("JSON parse error: ", err)
-		return nil
-	}
-
-	// Save pretty JSON to file
-	if err := ioutil.WriteFile("./text_response.txt", prettyJSON1.Bytes(), 0644); err != nil {
-		log.Fatal(err)
+		return nil, err
 	}
 
 	// Unmarshal into a slice of module_assignment
 	var result []module_assignment
 	if err := json.Unmarshal(body, &result); err != nil {
-		fmt.Println("Error unmarshaling JSON: ", err)
-		return nil
-	}
-
-	// Print the pretty JSON to the console as a string
-	fmt.Println(prettyJSON1.String())
-
-	// Save the final result as JSON to another file
-	resultsJSON, _ := json.Marshal(result)
-	var prettyJSON2 bytes.Buffer
-	if err := json.Indent(&prettyJSON2, resultsJSON, "", "\t"); err != nil {
-		log.Println("JSON parse error: ", err)
-		return nil
-	}
-
-	if err := ioutil.WriteFile("./os_results.txt", prettyJSON2.Bytes(), 0644); err != nil {
-		log.Fatal(err)
+		return nil, &CanvasError{Kind: ErrCanvasDecode, URL: url, Err: err}
 	}
 
-	return result
+	return result, nil
 }
 
-func GetModules() []module_info {
-	canvasApiKey := GetEnvVar("CANVAS_API")
-	url := "https://webcourses.ucf.edu/api/v1/courses/1461901/modules"
+func (c *CanvasClient) GetModules(ctx context.Context, course int) ([]module_info, error) {
+	url := c.url("/courses/%d/modules", course)
 
-	req, err := http.NewRequest("GET", url, nil)
+	body, err := c.GetPages(ctx, url)
 	if err != nil {
-		fmt.Println("error creating request:", err)
-		return []module_info{}
+		return nil, err
 	}
 
-	req.Header.Add("Authorization", "Bearer "+canvasApiKey)
-
-	client := &http.Client{}
-	resp, err := client.Do(req)
-	if err != nil {
-		fmt.Println("Error sending request: ", err)
-		return []module_info{}
-	}
-	defer resp.Body.Close()
-
-	body, err := ioutil.ReadAll(resp.Body)
-	if err != nil {
-		fmt.Println("Error reading response body:", err)
-		return []module_info{}
-	}
-
-	var prettyJSON1 bytes.Buffer
-	error := json.Indent(&prettyJSON1, body, "", "\t")
-	if error != nil {
-		log.Println("JSON parse error: ", error)
-
-		return []module_info{}
-	}
-
-	f, err := os.OpenFile("./text_response.txt", os.O_RDWR, 0644)
-	if err != nil {
-		log.Fatal(err)
-	}
-
-	n3, err := f.WriteString(prettyJSON1.String())
-	check(err)
-	fmt.Printf("wrote %d bytes\n", n3)
-
 	var result []module_info
-	json.Unmarshal(body, &result)
-	if err != nil {
-		fmt.Println("Error unmarshaling JSON: ", err)
-		return []module_info{}
+	if err := json.Unmarshal(body, &result); err != nil {
+		return nil, &CanvasError{Kind: ErrCanvasDecode, URL: url, Err: err}
 	}
 	fmt.Printf("Found %d assignments\n", len(result))
 	fmt.Println("Success from GetAll")
@@ -375,35 +266,26 @@ func GetModules() []module_info {
 		fmt.Println("name: " + assignment.Name)
 		fmt.Println("module id: " + string(assignment.Id))
 	}*/
-	resultsJSON, _ := json.Marshal(result)
-	var prettyJSON2 bytes.Buffer
-	error2 := json.Indent(&prettyJSON2, resultsJSON, "", "\t")
-	if error2 != nil {
-		log.Println("JSON parse error: ", error)
-
-		return []module_info{}
-	}
+	return result, nil
+}
 
-	f2, err := os.OpenFile("./os_results.txt", os.O_RDWR, 0644)
+func (c *CanvasClient) GetAllAssignmentsByModule(ctx context.Context, course int, quizzes []canvas_quiz) ([]assignment_due, error) {
+	Modules, err := c.GetModules(ctx, course)
 	if err != nil {
-		log.Fatal(err)
+		return nil, err
 	}
-
-	n2, err := f2.WriteString(prettyJSON2.String())
-	check(err)
-	fmt.Printf("wrote %d bytes\n", n2)
-	//fmt.Println(string(body))
-	return result
-}
-
-func GetAllAssignmentsByModule() []assignment_due {
-	Modules := GetModules()
 	var ModuleAssignments []module_assignment
 	//fmt.Printf("Modules: %d\n", len(Modules))
 
-	for _, module := range Modules {
-		moduleItems := GetModuleAssignments(module.Id) // Get all items in the module
-		for _, item := range moduleItems {
+	// Get all items of every module in parallel, the client limits the requests in flight
+	moduleItems, errs := runPool(ctx, len(Modules), func(ctx context.Context, i int) ([]module_assignment, error) {
+		return c.GetModuleAssignments(ctx, course, Modules[i].Id)
+	})
+	for i, items := range moduleItems {
+		if errs[i] != nil {
+			return nil, errs[i]
+		}
+		for _, item := range items {
 			//fmt.Println("ModuleAssignment.Type = " + item.Type)
 			//fmt.Println("ModuleAssignment.Title = " + item.Title)
 			if item.Type == "Assignment" || item.Type == "Quiz" {
@@ -414,67 +296,61 @@ func GetAllAssignmentsByModule() []assignment_due {
 
 	// You can now use ModuleAssignments as needed, e.g., print them or further process them
 	fmt.Println("Module Assignments:", ModuleAssignments)
-	fmt.Println("\n\n\n\n")
+	fmt.Print("\n\n\n\n\n")
 	var assignmentsArr []assignment_due
 
-	for _, assignment := range ModuleAssignments {
-		assignments := GetAssignmentById(assignment.Content_Id)
+	// Quiz items point at the quiz, not at the assignment behind it
+	assignmentIds := moduleAssignmentIds(ModuleAssignments, quizzes)
+
+	assignments, errs := runPool(ctx, len(assignmentIds), func(ctx context.Context, i int) (assignment_due, error) {
+		return c.GetAssignmentById(ctx, course, assignmentIds[i])
+	})
+	for i, assignment := range assignments {
+		if errors.Is(errs[i], ErrCanvasNotFound) {
+			// A missing item shouldn't cost the whole course
+			fmt.Println("Skipping module item:", errs[i])
+			continue
+		}
+		if errs[i] != nil {
+			return nil, errs[i]
+		}
 
-		assignmentsArr = append(assignmentsArr, assignments)
+		assignmentsArr = append(assignmentsArr, assignment)
 
 	}
 
-	return assignmentsArr
+	return assignmentsArr, nil
 }
 
-func GetAllAssignmentsByCourse(course int) []assignment_due {
-	canvasApiKey := GetEnvVar("CANVAS_API")
-	url := fmt.Sprintf("https://webcourses.ucf.edu/api/v1/users/4374518/courses/%d/assignments", course)
-	//fmt.Println(url)
-	req, err := http.NewRequest("GET", url, nil)
-	if err != nil {
-		fmt.Println("error creating request:", err)
-		return []assignment_due{}
-	}
-
-	req.Header.Add("Authorization", "Bearer "+canvasApiKey)
-
-	client := &http.Client{}
-	resp, err := client.Do(req)
-	if err != nil {
-		fmt.Println("Error sending request: ", err)
-		return []assignment_due{}
-	}
-	defer resp.Body.Close()
-
-	body, err := ioutil.ReadAll(resp.Body)
-	if err != nil {
-		fmt.Println("Error reading response body:", err)
-		return []assignment_due{}
-	}
-
-	var prettyJSON1 bytes.Buffer
-	error := json.Indent(&prettyJSON1, body, "", "\t")
-	if error != nil {
-		log.Println("JSON parse error: ", error)
-
-		return []assignment_due{}
+// Helper function to turn module items into assignment ids through the course's
+// quizzes. Quizzes without an assignment (practice quizzes, surveys) are left
+// out, GetQuizzes picks them up.
+func moduleAssignmentIds(items []module_assignment, quizzes []canvas_quiz) []int {
+	quizIds := quizAssignmentIds(quizzes)
+	ids := []int{}
+	for _, item := range items {
+		if item.Type != "Quiz" {
+			ids = append(ids, item.Content_Id)
+			continue
+		}
+		if id, ok := quizIds[item.Content_Id]; ok {
+			ids = append(ids, id)
+		}
 	}
+	return ids
+}
 
-	f, err := os.OpenFile("./text_response.txt", os.O_RDWR, 0644)
+func (c *CanvasClient) GetAllAssignmentsByCourse(ctx context.Context, course int) ([]assignment_due, error) {
+	url := c.url("/users/%s/courses/%d/assignments?include[]=submission&include[]=all_dates&include[]=overrides", c.UserID, course)
+	//fmt.Println(url)
+	body, err := c.GetPages(ctx, url)
 	if err != nil {
-		log.Fatal(err)
+		return nil, err
 	}
 
-	n3, err := f.WriteString(prettyJSON1.String())
-	check(err)
-	fmt.Printf("wrote %d bytes\n", n3)
-
 	var result []assignment_due
-	json.Unmarshal(body, &result)
-	if err != nil {
-		fmt.Println("Error unmarshaling JSON: ", err)
-		return []assignment_due{}
+	if err := json.Unmarshal(body, &result); err != nil {
+		return nil, &CanvasError{Kind: ErrCanvasDecode, URL: url, Err: err}
 	}
 	fmt.Printf("Found %d assignments\n", len(result))
 	fmt.Println("Success from GetAll")
@@ -482,99 +358,84 @@ func GetAllAssignmentsByCourse(course int) []assignment_due {
 		fmt.Println("name: " + assignment.Name)
 		fmt.Println("due at: " + assignment.Due_At)
 	}
-	resultsJSON, _ := json.Marshal(result)
-	var prettyJSON2 bytes.Buffer
-	error2 := json.Indent(&prettyJSON2, resultsJSON, "", "\t")
-	if error2 != nil {
-		log.Println("JSON parse error: ", error)
+	return result, nil
+}
 
-		return []assignment_due{}
-	}
+func (c *CanvasClient) GetDiscussionPostByCourse(ctx context.Context, course int) ([]discussion_due, error) {
+	url := c.url("/courses/%d/discussion_topics", course)
 
-	f2, err := os.OpenFile("./os_results.txt", os.O_RDWR, 0644)
+	body, err := c.GetPages(ctx, url)
 	if err != nil {
-		log.Fatal(err)
+		return nil, err
 	}
-
-	n2, err := f2.WriteString(prettyJSON2.String())
-	check(err)
-	fmt.Printf("wrote %d bytes\n", n2)
-	//fmt.Println(string(body))
-	return result
+	var result []discussion_due
+	if err := json.Unmarshal(body, &result); err != nil {
+		return nil, &CanvasError{Kind: ErrCanvasDecode, URL: url, Err: err}
+	}
+	fmt.Printf("Found %d discussions\n", len(result))
+	fmt.Println("Success from GetAll")
+	for _, discussion := range result {
+		fmt.Println("name: " + discussion.Title)
+		fmt.Println("due at: " + discussion.Assignment.Due_At)
+	}
+	return result, nil
 }
 
-func GetDiscussionPostByCourse(course int) []discussion_due {
-	canvasApiKey := GetEnvVar("CANVAS_API")
-	url := fmt.Sprintf("https://webcourses.ucf.edu/api/v1/courses/%d/discussion_topics", course)
-
-	req, err := http.NewRequest("GET", url, nil)
-	if err != nil {
-		fmt.Println("error creating request:", err)
-		return []discussion_due{}
-	}
+type canvas_term struct {
+	Id       int    `json:"id"`
+	Name     string `json:"name"`
+	Start_At string `json:"start_at"`
+	End_At   string `json:"end_at"`
+}
 
-	req.Header.Add("Authorization", "Bearer "+canvasApiKey)
+type canvas_course struct {
+	Id          int          `json:"id"`
+	Name        string       `json:"name"`
+	Course_Code string       `json:"course_code"`
+	Start_At    string       `json:"start_at"`
+	End_At      string       `json:"end_at"`
+	Term        *canvas_term `json:"term"`
+	// Whether the final grade is a weighted average of the assignment groups
+	Apply_Assignment_Group_Weights bool `json:"apply_assignment_group_weights"`
+}
 
-	client := &http.Client{}
-	resp, err := client.Do(req)
-	if err != nil {
-		fmt.Println("Error sending request: ", err)
-		return []discussion_due{}
-	}
-	defer resp.Body.Close()
+// GetActiveCourses returns every course the user has an active enrollment in
+func (c *CanvasClient) GetActiveCourses(ctx context.Context) ([]canvas_course, error) {
+	url := c.url("/courses?enrollment_state=active&include[]=term")
 
-	body, err := ioutil.ReadAll(resp.Body)
+	body, err := c.GetPages(ctx, url)
 	if err != nil {
-		fmt.Println("Error reading response body:", err)
-		return []discussion_due{}
+		return nil, err
 	}
-	//fmt.Println("Raw response body:", string(body))
-	var prettyJSON1 bytes.Buffer
-	error := json.Indent(&prettyJSON1, body, "", "\t")
-	if error != nil {
-		log.Println("JSON parse error: ", error)
 
-		return []discussion_due{}
+	var result []canvas_course
+	if err := json.Unmarshal(body, &result); err != nil {
+		return nil, &CanvasError{Kind: ErrCanvasDecode, URL: url, Err: err}
 	}
+	fmt.Printf("Found %d active courses\n", len(result))
+	return result, nil
+}
 
-	f, err := os.OpenFile("./discussion_response.txt", os.O_RDWR, 0644)
-	if err != nil {
-		log.Fatal(err)
+// Check if a course belongs to the wanted term, or to a term running right now when no term is given
+func (c canvas_course) InTerm(term string, now time.Time) bool {
+	if term != "" {
+		return c.Term != nil && c.Term.Name == term
 	}
 
-	n3, err := f.WriteString(prettyJSON1.String())
-	check(err)
-	fmt.Printf("wrote %d bytes\n", n3)
-
-	var result []discussion_due
-	json.Unmarshal(body, &result)
-	if err != nil {
-		fmt.Println("Error unmarshaling JSON: ", err)
-		return []discussion_due{}
-	}
-	fmt.Printf("Found %d discussions\n", len(result))
-	fmt.Println("Success from GetAll")
-	for _, discussion := range result {
-		fmt.Println("name: " + discussion.Title)
-		fmt.Println("due at: " + discussion.Assignment.Due_At)
+	startAt, endAt := c.Start_At, c.End_At
+	if c.Term != nil {
+		if c.Term.Start_At != "" {
+			startAt = c.Term.Start_At
+		}
+		if c.Term.End_At != "" {
+			endAt = c.Term.End_At
+		}
 	}
-	resultsJSON, _ := json.Marshal(result)
-	var prettyJSON2 bytes.Buffer
-	error2 := json.Indent(&prettyJSON2, resultsJSON, "", "\t")
-	if error2 != nil {
-		log.Println("JSON parse error: ", error)
-
-		return []discussion_due{}
+	if start, err := time.Parse(time.RFC3339, startAt); err == nil && now.Before(start) {
+		return false
 	}
-
-	f2, err := os.OpenFile("./disuccsion_results.txt", os.O_RDWR, 0644)
-	if err != nil {
-		log.Fatal(err)
+	if end, err := time.Parse(time.RFC3339, endAt); err == nil && now.After(end) {
+		return false
 	}
-
-	n2, err := f2.WriteString(prettyJSON2.String())
-	check(err)
-	fmt.Printf("wrote %d bytes\n", n2)
-	//fmt.Println(string(body))
-	return result
+	return true
 }
@@ -0,0 +1,172 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// Helper function to decode a saved Canvas response from testdata
+func decodeFixture(t *testing.T, name string, v any) {
+	t.Helper()
+	data, err := os.ReadFile(filepath.Join("testdata", name))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := json.Unmarshal(data, v); err != nil {
+		t.Fatalf("decoding %s: %v", name, err)
+	}
+}
+
+// Helper function to keep the fields of an assignment the golden tests check
+func modeledAssignmentFields(a assignment_due) assignment_due {
+	return assignment_due{
+		Id:               a.Id,
+		Name:             a.Name,
+		Due_At:           a.Due_At,
+		Course_Id:        a.Course_Id,
+		Html_Url:         a.Html_Url,
+		Points_Possible:  a.Points_Possible,
+		Lock_At:          a.Lock_At,
+		Unlock_At:        a.Unlock_At,
+		Todo_Date:        a.Todo_Date,
+		Submission_Types: a.Submission_Types,
+		Published:        a.Published,
+		Workflow_State:   a.Workflow_State,
+		Locked_For_User:  a.Locked_For_User,
+	}
+}
+
+func TestDecodeAssignmentsFixture(t *testing.T) {
+	var assignments []assignment_due
+	decodeFixture(t, "text_response.txt", &assignments)
+	if len(assignments) != 10 {
+		t.Fatalf("got %d assignments, want 10", len(assignments))
+	}
+
+	want := map[int]assignment_due{
+		8608633: {
+			Id:               8608633,
+			Name:             "PA#1 - The ChatGPT Scheduler",
+			Due_At:           "2024-10-02T03:59:00Z",
+			Course_Id:        1464092,
+			Html_Url:         "https://webcourses.ucf.edu/courses/1464092/assignments/8608633",
+			Points_Possible:  100,
+			Submission_Types: []string{"online_upload"},
+			Published:        true,
+			Workflow_State:   "published",
+		},
+		8608624: {
+			Id:               8608624,
+			Name:             "Quiz 3 - Virtual Memory",
+			Due_At:           "2024-09-09T03:59:00Z",
+			Course_Id:        1464092,
+			Html_Url:         "https://webcourses.ucf.edu/courses/1464092/assignments/8608624",
+			Points_Possible:  10,
+			Lock_At:          "2024-09-09T03:59:00Z",
+			Unlock_At:        "2024-09-06T04:00:00Z",
+			Submission_Types: []string{"online_quiz"},
+			Published:        true,
+			Workflow_State:   "published",
+			Locked_For_User:  true,
+		},
+	}
+	for _, assignment := range assignments {
+		if assignment.Html_Url == "" || assignment.Course_Id == 0 || len(assignment.Submission_Types) == 0 {
+			t.Errorf("assignment %d is missing html_url, course_id or submission_types", assignment.Id)
+		}
+		w, ok := want[assignment.Id]
+		if !ok {
+			continue
+		}
+		delete(want, assignment.Id)
+		if got := modeledAssignmentFields(assignment); !reflect.DeepEqual(got, w) {
+			t.Errorf("assignment %d:\n got %+v\nwant %+v", assignment.Id, got, w)
+		}
+	}
+	for id := range want {
+		t.Errorf("assignment %d not in the fixture", id)
+	}
+}
+
+func TestDecodeDiscussionsFixture(t *testing.T) {
+	var discussions []discussion_due
+	decodeFixture(t, "discussion_topics.json", &discussions)
+	if len(discussions) != 3 {
+		t.Fatalf("got %d discussions, want 3", len(discussions))
+	}
+
+	graded := discussions[0]
+	if graded.Id != 7761140 || graded.Title != "Case study: Is It a Meteorite? Applying Problem-Solving Strategy to Mass Spectra and Atomic Mass" {
+		t.Errorf("first discussion = %d %q", graded.Id, graded.Title)
+	}
+	if graded.Html_Url != "https://webcourses.ucf.edu/courses/1461901/discussion_topics/7761140" {
+		t.Errorf("html_url = %q", graded.Html_Url)
+	}
+	if graded.Created_At != "2024-07-11T16:21:13Z" {
+		t.Errorf("created_at = %q, want 2024-07-11T16:21:13Z", graded.Created_At)
+	}
+	if graded.Assignment_Id != 8554246 || graded.Todo_Date != "" || graded.Lock_At != "" || !graded.Published {
+		t.Errorf("discussion fields = %+v", graded)
+	}
+	wantAssignment := assignment_due{
+		Id:               8554246,
+		Name:             graded.Title,
+		Due_At:           "2024-09-09T03:59:00Z",
+		Course_Id:        1461901,
+		Html_Url:         "https://webcourses.ucf.edu/courses/1461901/assignments/8554246",
+		Points_Possible:  10,
+		Lock_At:          "2024-09-10T03:59:00Z",
+		Submission_Types: []string{"discussion_topic"},
+		Published:        true,
+		Workflow_State:   "published",
+	}
+	if got := modeledAssignmentFields(graded.Assignment); !reflect.DeepEqual(got, wantAssignment) {
+		t.Errorf("graded discussion assignment:\n got %+v\nwant %+v", got, wantAssignment)
+	}
+
+	// locked_for_user comes through on the topic and on its assignment
+	if locked := discussions[1]; !locked.Locked_For_User || !locked.Assignment.Locked_For_User {
+		t.Errorf("discussion %d should be locked for me, got %v / %v", locked.Id, locked.Locked_For_User, locked.Assignment.Locked_For_User)
+	}
+	if ungraded := discussions[2]; ungraded.Assignment_Id != 0 || ungraded.Assignment.Id != 0 || ungraded.Locked_For_User {
+		t.Errorf("ungraded discussion %d = %+v", ungraded.Id, ungraded)
+	}
+}
+
+func TestDecodeOldResultsFixture(t *testing.T) {
+	// Written by an old run with the broken tags, so only the basic fields are there
+	var assignments []assignment_due
+	decodeFixture(t, "os_results.txt", &assignments)
+	if len(assignments) != 10 {
+		t.Fatalf("got %d assignments, want 10", len(assignments))
+	}
+	if first := assignments[0]; first.Id != 8608633 || first.Name != "PA#1 - The ChatGPT Scheduler" || first.Due_At != "2024-10-02T03:59:00Z" {
+		t.Errorf("first assignment = %+v", first)
+	}
+}
+
+func TestAssignmentSubmitted(t *testing.T) {
+	tests := []struct {
+		name       string
+		submission *canvas_submission
+		want       bool
+	}{
+		{"no submission", nil, false},
+		{"unsubmitted", &canvas_submission{Workflow_State: "unsubmitted"}, false},
+		{"submitted", &canvas_submission{Workflow_State: "submitted", Submitted_At: "2024-10-01T20:00:00Z"}, true},
+		{"graded after submitting", &canvas_submission{Workflow_State: "graded", Submitted_At: "2024-10-01T20:00:00Z"}, true},
+		{"pending review", &canvas_submission{Workflow_State: "pending_review"}, true},
+		{"graded on paper", &canvas_submission{Workflow_State: "graded"}, false},
+		{"missing and graded to 0", &canvas_submission{Workflow_State: "graded", Missing: true}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := (assignment_due{Submission: tt.submission}).Submitted(); got != tt.want {
+				t.Errorf("Submitted() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,110 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+)
+
+// Canvas sends an ETag on its list endpoints. Responses are kept per URL so
+// the next run can send If-None-Match and reuse the body when Canvas answers
+// 304 Not Modified. Where they are kept is up to CANVAS_CACHE:
+//
+//	disk  files under CANVAS_CACHE_DIR (default)
+//	redis the REDIS_HOST Redis server
+//	gcs   the CANVAS_CACHE_BUCKET bucket
+//	off   no caching
+
+const (
+	CacheDisk  = "disk"
+	CacheRedis = "redis"
+	CacheGCS   = "gcs"
+	CacheOff   = "off"
+)
+
+// Redis entries outlive a few weeks of daily runs but don't pile up forever
+const canvasRedisCacheExpires = 30 * 24 * 60 * 60
+
+// A Canvas response as kept in the cache. The Link header is kept too, so
+// pagination still works when a page comes back 304.
+type cachedResponse struct {
+	URL  string `json:"url"`
+	ETag string `json:"etag"`
+	Link string `json:"link"`
+	Body []byte `json:"body"`
+}
+
+var canvasCacheOnce struct {
+	sync.Once
+	cache RestApiCache
+}
+
+// Helper function to build the cache backend shared by every Canvas client, nil when off
+func canvasCache() RestApiCache {
+	canvasCacheOnce.Do(func() {
+		backend := GetEnvVar("CANVAS_CACHE", CacheDisk, "", "cache")
+		switch backend {
+		case CacheDisk:
+			canvasCacheOnce.cache = DiskRestApiCache{Dir: GetEnvVar("CANVAS_CACHE_DIR", ".cache")}
+		case CacheRedis:
+			cache, err := NewRedisRestApiCache(
+				GetEnvVar("REDIS_HOST", "localhost"),
+				GetEnvVar("REDIS_PORT", "6379"),
+				GetEnvVar("REDIS_PASS"),
+				GetEnvVarBool("REDIS_TLS", false),
+				"chatgptnotionplanner:",
+				canvasRedisCacheExpires,
+			)
+			if err != nil {
+				fmt.Println("Error connecting to the Redis cache, Canvas caching is off:", err)
+				return
+			}
+			canvasCacheOnce.cache = cache
+		case CacheGCS:
+			canvasCacheOnce.cache = GcsRestApiCache{Bucket: GetEnvVar("CANVAS_CACHE_BUCKET")}
+		case CacheOff, "":
+		default:
+			fmt.Println("Unknown CANVAS_CACHE backend, Canvas caching is off:", backend)
+		}
+	})
+	return canvasCacheOnce.cache
+}
+
+// Helper function to build the cache key of a URL. The token is part of it so
+// two users of the same Canvas never share "self" responses.
+func (c *CanvasClient) cacheKey(rawURL string) string {
+	return "canvas/" + HashStr(c.Token+" "+rawURL) + ".json"
+}
+
+// Helper function to load the cached response of a URL, if there is a usable one
+func (c *CanvasClient) loadCached(rawURL string) (cachedResponse, bool) {
+	var cached cachedResponse
+	if c.Cache == nil {
+		return cached, false
+	}
+	data, _, found, err := c.Cache.Load(c.cacheKey(rawURL))
+	if err != nil {
+		fmt.Println("Error reading Canvas cache:", err)
+		return cached, false
+	}
+	if !found || json.Unmarshal(data, &cached) != nil || cached.ETag == "" || cached.URL != rawURL {
+		return cached, false
+	}
+	return cached, true
+}
+
+// Helper function to cache a 200 response that came with an ETag
+func (c *CanvasClient) saveCached(rawURL string, header http.Header, body []byte) {
+	etag := header.Get("ETag")
+	if c.Cache == nil || etag == "" {
+		return
+	}
+	data, err := json.Marshal(cachedResponse{URL: rawURL, ETag: etag, Link: header.Get("Link"), Body: body})
+	if err == nil {
+		err = c.Cache.Save(c.cacheKey(rawURL), data)
+	}
+	if err != nil {
+		fmt.Println("Error writing Canvas cache:", err)
+	}
+}
@@ -0,0 +1,431 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const defaultCanvasBaseURL = "https://webcourses.ucf.edu"
+
+// CanvasClient talks to one Canvas instance as one user. Build one per institution
+// to pull several Canvas instances into the same run.
+type CanvasClient struct {
+	Name    string // source tag put on every item fetched through this client
+	BaseURL string
+	Token   string
+	UserID  string // "self" works for the token's own user
+	client  *http.Client
+
+	CaptureDir string       // when set, every raw response is saved here
+	ReplayDir  string       // when set, responses are read from captures here instead of Canvas
+	Cache      RestApiCache // ETag cache, nil when caching is off
+
+	// Read once here, settings must never be read from a worker: GetEnvVar
+	// goes through the global viper, which isn't safe for concurrent use
+	PerPage     int // per_page sent with list requests
+	Concurrency int // requests that may be in flight to this instance at once
+
+	// Course ids the config turns off, skipped wherever Canvas lists them unasked
+	DisabledCourses map[int]bool
+
+	// Every worker sharing the client waits until pausedUntil once Canvas
+	// says the rate-limit bucket is running low
+	mu          sync.Mutex
+	pausedUntil time.Time
+	// One slot per request in flight, however many pools the workers come from.
+	// Made on first use with room for Concurrency.
+	inFlight chan struct{}
+	// My enrollments per course, fetched once and shared by the date resolution and the grades
+	enrollments map[int][]canvas_enrollment
+}
+
+func NewCanvasClient(name, baseURL, token, userID string) *CanvasClient {
+	if baseURL == "" {
+		baseURL = defaultCanvasBaseURL
+	}
+	if userID == "" {
+		userID = "self"
+	}
+	return &CanvasClient{
+		Name:    name,
+		BaseURL: strings.TrimRight(baseURL, "/"),
+		Token:   token,
+		UserID:  userID,
+		client:  &http.Client{},
+
+		CaptureDir: canvasCaptureDir(),
+		ReplayDir:  canvasReplayDir(),
+		Cache:      canvasCache(),
+
+		PerPage:     canvasPerPage(),
+		Concurrency: canvasConcurrency(),
+	}
+}
+
+// NewDefaultCanvasClient builds the client for the CANVAS_* settings in .env or the environment
+func NewDefaultCanvasClient() *CanvasClient {
+	return NewCanvasClient(
+		GetEnvVar("CANVAS_NAME"),
+		GetEnvVar("CANVAS_BASE_URL", defaultCanvasBaseURL),
+		GetEnvVar("CANVAS_API"),
+		GetEnvVar("CANVAS_USER_ID", "self"),
+	)
+}
+
+// Helper function to build a full Canvas API url from a path like /courses/%d/assignments
+func (c *CanvasClient) url(path string, args ...any) string {
+	return c.BaseURL + "/api/v1" + fmt.Sprintf(path, args...)
+}
+
+// Helper function to build an authenticated GET request
+func (c *CanvasClient) newRequest(ctx context.Context, rawURL string) (*http.Request, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", rawURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Add("Authorization", "Bearer "+c.Token)
+	return req, nil
+}
+
+var (
+	ErrCanvasUnauthorized = errors.New("canvas token is missing, expired or not allowed")
+	ErrCanvasNotFound     = errors.New("canvas resource not found")
+	ErrCanvasDisabled     = errors.New("canvas page is disabled for this course")
+	ErrCanvasRateLimited  = errors.New("canvas rate limit exceeded")
+	ErrCanvasDecode       = errors.New("could not decode canvas response")
+	ErrCanvasStatus       = errors.New("unexpected canvas response status")
+)
+
+// CanvasError wraps a failed Canvas call. Kind is one of the ErrCanvas* values
+// above, so callers can check it with errors.Is.
+type CanvasError struct {
+	Kind       error
+	StatusCode int
+	URL        string
+	Err        error
+}
+
+func (e *CanvasError) Error() string {
+	msg := e.Kind.Error()
+	if e.StatusCode != 0 {
+		msg += fmt.Sprintf(" (status %d)", e.StatusCode)
+	}
+	if e.URL != "" {
+		msg += " for " + e.URL
+	}
+	if e.Err != nil {
+		msg += ": " + e.Err.Error()
+	}
+	return msg
+}
+
+func (e *CanvasError) Is(target error) bool { return target == e.Kind }
+func (e *CanvasError) Unwrap() error        { return e.Err }
+
+// Helper function to check if a 403 is Canvas throttling rather than a permission
+// error. Canvas sends X-Rate-Limit-Remaining on every response, so only an empty
+// bucket or its "Rate Limit Exceeded" message count.
+func isRateLimited(resp *http.Response, body []byte) bool {
+	remaining, err := strconv.ParseFloat(resp.Header.Get("X-Rate-Limit-Remaining"), 64)
+	if err == nil && remaining <= 0 {
+		return true
+	}
+	return strings.Contains(string(body), "Rate Limit Exceeded")
+}
+
+// Helper function to check if Canvas refused because the course has the page
+// turned off, ex. "That page has been disabled for this course"
+func isPageDisabled(body []byte) bool {
+	return strings.Contains(string(body), "has been disabled")
+}
+
+// Helper function to turn a non-200 Canvas response into a typed error
+func checkCanvasResponse(resp *http.Response, body []byte, rawURL string) error {
+	switch {
+	case resp.StatusCode == http.StatusOK:
+		return nil
+	case (resp.StatusCode == http.StatusUnauthorized || resp.StatusCode == http.StatusForbidden) && isPageDisabled(body):
+		return &CanvasError{Kind: ErrCanvasDisabled, StatusCode: resp.StatusCode, URL: rawURL}
+	case resp.StatusCode == http.StatusUnauthorized:
+		return &CanvasError{Kind: ErrCanvasUnauthorized, StatusCode: resp.StatusCode, URL: rawURL}
+	case resp.StatusCode == http.StatusNotFound:
+		return &CanvasError{Kind: ErrCanvasNotFound, StatusCode: resp.StatusCode, URL: rawURL}
+	case resp.StatusCode == http.StatusForbidden && isRateLimited(resp, body):
+		// Canvas throttles with a 403 too
+		return &CanvasError{Kind: ErrCanvasRateLimited, StatusCode: resp.StatusCode, URL: rawURL}
+	case resp.StatusCode == http.StatusForbidden:
+		return &CanvasError{Kind: ErrCanvasUnauthorized, StatusCode: resp.StatusCode, URL: rawURL}
+	default:
+		return &CanvasError{Kind: ErrCanvasStatus, StatusCode: resp.StatusCode, URL: rawURL}
+	}
+}
+
+// Canvas starts every token with a bucket of 700 and refills it over time.
+// Below canvasLowRateLimit we slow down before Canvas starts refusing requests.
+const (
+	canvasLowRateLimit   = 100
+	canvasLowLimitPause  = 2 * time.Second
+	canvasMaxRetries     = 5
+	canvasRetryBaseDelay = 2 * time.Second
+)
+
+// Helper function to hold every worker on this client until d from now
+func (c *CanvasClient) pause(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if until := time.Now().Add(d); until.After(c.pausedUntil) {
+		c.pausedUntil = until
+	}
+}
+
+// Helper function to wait out a pause, returning early if ctx is cancelled
+func (c *CanvasClient) waitForRateLimit(ctx context.Context) error {
+	c.mu.Lock()
+	wait := time.Until(c.pausedUntil)
+	c.mu.Unlock()
+	if wait <= 0 {
+		return ctx.Err()
+	}
+	timer := time.NewTimer(wait)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Helper function to take one of the client's request slots, waiting for a free
+// one unless ctx is done first. The returned function gives the slot back.
+func (c *CanvasClient) acquire(ctx context.Context) (func(), error) {
+	c.mu.Lock()
+	if c.inFlight == nil {
+		limit := c.Concurrency
+		if limit < 1 {
+			limit = defaultCanvasConcurrency
+		}
+		c.inFlight = make(chan struct{}, limit)
+	}
+	inFlight := c.inFlight
+	c.mu.Unlock()
+
+	select {
+	case inFlight <- struct{}{}:
+		return func() { <-inFlight }, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// Helper function to pause the client when the X-Rate-Limit-Remaining header says the bucket is low
+func (c *CanvasClient) noteRateLimit(resp *http.Response) {
+	remaining, err := strconv.ParseFloat(resp.Header.Get("X-Rate-Limit-Remaining"), 64)
+	if err == nil && remaining < canvasLowRateLimit {
+		c.pause(canvasLowLimitPause)
+	}
+}
+
+// do sends one authenticated GET and returns the response headers along with
+// the body. It holds one of the client's request slots while the request is in
+// flight, but not while backing off. Rate-limited requests are retried with an
+// exponential back-off, and a cached body is reused when Canvas says it hasn't changed.
+func (c *CanvasClient) do(ctx context.Context, rawURL string) (http.Header, []byte, error) {
+	cached, hasCached := c.loadCached(rawURL)
+
+	for attempt := 0; ; attempt++ {
+		if err := c.waitForRateLimit(ctx); err != nil {
+			return nil, nil, err
+		}
+
+		req, err := c.newRequest(ctx, rawURL)
+		if err != nil {
+			return nil, nil, fmt.Errorf("error creating request: %w", err)
+		}
+		if hasCached {
+			req.Header.Set("If-None-Match", cached.ETag)
+		}
+
+		release, err := c.acquire(ctx)
+		if err != nil {
+			return nil, nil, err
+		}
+		resp, err := c.client.Do(req)
+		if err != nil {
+			release()
+			return nil, nil, fmt.Errorf("error sending request: %w", err)
+		}
+
+		body, err := ioutil.ReadAll(resp.Body)
+		resp.Body.Close()
+		release()
+		if err != nil {
+			return nil, nil, fmt.Errorf("error reading response body: %w", err)
+		}
+
+		c.noteRateLimit(resp)
+		if resp.StatusCode == http.StatusNotModified && hasCached {
+			header := resp.Header.Clone()
+			header.Set("Link", cached.Link)
+			return header, cached.Body, nil
+		}
+
+		err = checkCanvasResponse(resp, body, rawURL)
+		if errors.Is(err, ErrCanvasRateLimited) && attempt < canvasMaxRetries {
+			delay := canvasRetryBaseDelay << attempt
+			fmt.Printf("Canvas rate limit hit, retrying in %s\n", delay)
+			c.pause(delay)
+			continue
+		}
+		if err == nil {
+			c.saveCached(rawURL, resp.Header, body)
+		}
+		return resp.Header, body, err
+	}
+}
+
+// Helper function to save a raw response when capturing is on. A failed
+// capture is only reported, it never fails the fetch.
+func (c *CanvasClient) capture(rawURL string, body []byte) {
+	if c.CaptureDir == "" {
+		return
+	}
+	if err := writeCapture(c.CaptureDir, rawURL, body); err != nil {
+		fmt.Println("Error capturing Canvas response:", err)
+	}
+}
+
+// Get fetches a single Canvas resource and returns the raw body
+func (c *CanvasClient) Get(ctx context.Context, rawURL string) ([]byte, error) {
+	if c.ReplayDir != "" {
+		return readCapture(c.ReplayDir, rawURL)
+	}
+
+	_, body, err := c.do(ctx, rawURL)
+	if err != nil {
+		return nil, err
+	}
+	c.capture(rawURL, body)
+	return body, nil
+}
+
+// Canvas returns 10 items per page unless told otherwise and caps per_page at 100
+const canvasMaxPerPage = 100
+
+var canvasLinkRegex = regexp.MustCompile(`<([^>]+)>\s*;\s*rel="([^"]+)"`)
+
+// Helper function to read the per_page value from the environment
+func canvasPerPage() int {
+	return int(GetEnvVarInt64("CANVAS_PER_PAGE", canvasMaxPerPage, 1, canvasMaxPerPage))
+}
+
+// Helper function to add the per_page query param to a Canvas list URL
+func withPerPage(rawURL string, perPage int) (string, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return "", err
+	}
+	q := u.Query()
+	if q.Get("per_page") == "" {
+		q.Set("per_page", strconv.Itoa(perPage))
+	}
+	u.RawQuery = q.Encode()
+	return u.String(), nil
+}
+
+// Parse a Canvas Link header into a map of rel -> url
+func parseLinkHeader(header string) map[string]string {
+	links := make(map[string]string)
+	for _, match := range canvasLinkRegex.FindAllStringSubmatch(header, -1) {
+		links[match[2]] = match[1]
+	}
+	return links
+}
+
+// GetPages fetches a Canvas list endpoint, follows every rel="next" link
+// and returns all pages merged into a single JSON array
+func (c *CanvasClient) GetPages(ctx context.Context, rawURL string) ([]byte, error) {
+	if c.ReplayDir != "" {
+		return readCapture(c.ReplayDir, rawURL)
+	}
+
+	perPage := c.PerPage
+	if perPage < 1 {
+		perPage = canvasMaxPerPage
+	}
+	next, err := withPerPage(rawURL, perPage)
+	if err != nil {
+		return nil, fmt.Errorf("error parsing url %s: %w", rawURL, err)
+	}
+
+	merged := []json.RawMessage{}
+	seen := make(map[string]bool)
+
+	for next != "" && !seen[next] {
+		seen[next] = true
+
+		header, body, err := c.do(ctx, next)
+		if err != nil {
+			return nil, err
+		}
+
+		var page []json.RawMessage
+		if err := json.Unmarshal(body, &page); err != nil {
+			return nil, &CanvasError{Kind: ErrCanvasDecode, URL: next, Err: err}
+		}
+		merged = append(merged, page...)
+
+		next = parseLinkHeader(header.Get("Link"))["next"]
+	}
+
+	body, err := json.Marshal(merged)
+	if err != nil {
+		return nil, err
+	}
+	c.capture(rawURL, body)
+	return body, nil
+}
+
+// Canvas rejects calendar_events and announcements requests with more than 10 context codes
+const canvasMaxContextCodes = 10
+
+// getContextPages fetches a list endpoint that takes context_codes[] for every
+// context, in batches Canvas accepts, and decodes all pages into one slice
+func getContextPages[T any](ctx context.Context, c *CanvasClient, path string, query url.Values, contextCodes []string) ([]T, error) {
+	result := []T{}
+	for len(contextCodes) > 0 {
+		batch := contextCodes
+		if len(batch) > canvasMaxContextCodes {
+			batch = batch[:canvasMaxContextCodes]
+		}
+		contextCodes = contextCodes[len(batch):]
+
+		batchQuery := url.Values{}
+		for key, values := range query {
+			batchQuery[key] = values
+		}
+		batchQuery["context_codes[]"] = batch
+		url := c.url(path+"?%s", batchQuery.Encode())
+
+		body, err := c.GetPages(ctx, url)
+		if err != nil {
+			return nil, err
+		}
+		var page []T
+		if err := json.Unmarshal(body, &page); err != nil {
+			return nil, &CanvasError{Kind: ErrCanvasDecode, URL: url, Err: err}
+		}
+		result = append(result, page...)
+	}
+	return result, nil
+}
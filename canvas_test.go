@@ -0,0 +1,346 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// Helper function to build a client against a fake Canvas server, with capture,
+// replay and caching off
+func newTestCanvasClient(srv *httptest.Server) *CanvasClient {
+	return &CanvasClient{
+		Name:    "test",
+		BaseURL: srv.URL,
+		Token:   "test-token",
+		UserID:  "self",
+		client:  srv.Client(),
+	}
+}
+
+// Helper function to serve pages of {"id": n} objects, linking each page to the next
+func fakeCanvasPages(t *testing.T, pages [][]int, requests *[]string) *httptest.Server {
+	t.Helper()
+	var srv *httptest.Server
+	srv = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		*requests = append(*requests, r.URL.RequestURI())
+		if got := r.Header.Get("Authorization"); got != "Bearer test-token" {
+			t.Errorf("Authorization = %q, want the client's token", got)
+		}
+
+		page := 0
+		fmt.Sscan(r.URL.Query().Get("page"), &page)
+		if page >= len(pages) {
+			http.NotFound(w, r)
+			return
+		}
+		if page+1 < len(pages) {
+			next := fmt.Sprintf("%s%s?page=%d&per_page=%s", srv.URL, r.URL.Path, page+1, r.URL.Query().Get("per_page"))
+			w.Header().Set("Link", `<`+next+`>; rel="next", <`+srv.URL+r.URL.Path+`?page=0>; rel="first"`)
+		}
+		items := []map[string]int{}
+		for _, id := range pages[page] {
+			items = append(items, map[string]int{"id": id})
+		}
+		json.NewEncoder(w).Encode(items)
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+// Helper function to read the ids out of a merged GetPages body
+func pageIDs(t *testing.T, body []byte) []int {
+	t.Helper()
+	var items []struct {
+		Id int `json:"id"`
+	}
+	if err := json.Unmarshal(body, &items); err != nil {
+		t.Fatalf("merged body is not a JSON array: %v", err)
+	}
+	ids := []int{}
+	for _, item := range items {
+		ids = append(ids, item.Id)
+	}
+	return ids
+}
+
+func TestGetPagesFollowsLinks(t *testing.T) {
+	requests := []string{}
+	srv := fakeCanvasPages(t, [][]int{{1, 2}, {3, 4}, {5}}, &requests)
+	c := newTestCanvasClient(srv)
+
+	body, err := c.GetPages(context.Background(), c.url("/courses/%d/assignments", 42))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := fmt.Sprint(pageIDs(t, body)), "[1 2 3 4 5]"; got != want {
+		t.Errorf("ids = %s, want %s", got, want)
+	}
+	if len(requests) != 3 {
+		t.Errorf("made %d requests, want 3: %v", len(requests), requests)
+	}
+}
+
+func TestGetPagesPerPage(t *testing.T) {
+	tests := []struct {
+		name    string
+		perPage int
+		path    string
+		want    string
+	}{
+		{"default", 0, "/courses/42/assignments", "per_page=100"},
+		{"from the client", 25, "/courses/42/assignments", "per_page=25"},
+		{"added next to other params", 25, "/courses/42/assignments?include[]=submission", "per_page=25"},
+		{"kept when set", 25, "/courses/42/assignments?per_page=7", "per_page=7"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			requests := []string{}
+			srv := fakeCanvasPages(t, [][]int{{1}}, &requests)
+			c := newTestCanvasClient(srv)
+			c.PerPage = tt.perPage
+
+			if _, err := c.GetPages(context.Background(), c.url(tt.path)); err != nil {
+				t.Fatal(err)
+			}
+			if len(requests) != 1 || !strings.Contains(requests[0], tt.want) {
+				t.Errorf("requests = %v, want one with %s", requests, tt.want)
+			}
+			if strings.Contains(tt.path, "include") && !strings.Contains(requests[0], "include") {
+				t.Errorf("request %s lost the include param", requests[0])
+			}
+		})
+	}
+}
+
+func TestGetPagesStopsOnLinkLoop(t *testing.T) {
+	requests := []string{}
+	var srv *httptest.Server
+	srv = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests = append(requests, r.URL.RequestURI())
+		// Every page says the next one is page 2, including page 2 itself
+		w.Header().Set("Link", `<`+srv.URL+r.URL.Path+`?page=2&per_page=100>; rel="next"`)
+		fmt.Fprint(w, `[{"id": 1}]`)
+	}))
+	defer srv.Close()
+	c := newTestCanvasClient(srv)
+
+	body, err := c.GetPages(context.Background(), c.url("/courses/42/assignments"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(requests) != 2 {
+		t.Errorf("made %d requests, want 2: %v", len(requests), requests)
+	}
+	if got := len(pageIDs(t, body)); got != 2 {
+		t.Errorf("got %d items, want 2", got)
+	}
+}
+
+func TestGetPagesErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		status int
+		want   error
+	}{
+		{"not found", http.StatusNotFound, ErrCanvasNotFound},
+		{"unauthorized", http.StatusUnauthorized, ErrCanvasUnauthorized},
+		{"forbidden", http.StatusForbidden, ErrCanvasUnauthorized},
+		{"server error", http.StatusInternalServerError, ErrCanvasStatus},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var srv *httptest.Server
+			srv = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				// The first page is fine, the second one fails
+				if r.URL.Query().Get("page") == "" {
+					w.Header().Set("Link", `<`+srv.URL+r.URL.Path+`?page=2>; rel="next"`)
+					fmt.Fprint(w, `[{"id": 1}]`)
+					return
+				}
+				w.WriteHeader(tt.status)
+				fmt.Fprint(w, `{"errors": [{"message": "nope"}]}`)
+			}))
+			defer srv.Close()
+			c := newTestCanvasClient(srv)
+
+			body, err := c.GetPages(context.Background(), c.url("/courses/42/assignments"))
+			if !errors.Is(err, tt.want) {
+				t.Fatalf("err = %v, want %v", err, tt.want)
+			}
+			var canvasErr *CanvasError
+			if !errors.As(err, &canvasErr) || canvasErr.StatusCode != tt.status {
+				t.Errorf("err = %#v, want a *CanvasError with status %d", err, tt.status)
+			}
+			if body != nil {
+				t.Errorf("body = %s, want none for a failed list", body)
+			}
+		})
+	}
+}
+
+func TestGetPagesDecodeError(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"not": "a list"}`)
+	}))
+	defer srv.Close()
+	c := newTestCanvasClient(srv)
+
+	if _, err := c.GetPages(context.Background(), c.url("/courses/42/assignments")); !errors.Is(err, ErrCanvasDecode) {
+		t.Errorf("err = %v, want %v", err, ErrCanvasDecode)
+	}
+}
+
+func TestCheckCanvasResponseForbidden(t *testing.T) {
+	tests := []struct {
+		name      string
+		remaining string
+		body      string
+		want      error
+	}{
+		{"permission error with a full bucket", "650.5", `{"status": "unauthorized"}`, ErrCanvasUnauthorized},
+		{"permission error without the header", "", `{"status": "unauthorized"}`, ErrCanvasUnauthorized},
+		{"empty bucket", "0.0", "", ErrCanvasRateLimited},
+		{"rate limit message", "12.3", "403 Forbidden (Rate Limit Exceeded)", ErrCanvasRateLimited},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp := &http.Response{StatusCode: http.StatusForbidden, Header: http.Header{}}
+			if tt.remaining != "" {
+				resp.Header.Set("X-Rate-Limit-Remaining", tt.remaining)
+			}
+			if err := checkCanvasResponse(resp, []byte(tt.body), "https://canvas.test/api/v1/courses/42"); !errors.Is(err, tt.want) {
+				t.Errorf("err = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestGetPagesDoesNotRetryPermissionErrors(t *testing.T) {
+	requests := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		w.Header().Set("X-Rate-Limit-Remaining", "650.0")
+		w.WriteHeader(http.StatusForbidden)
+		fmt.Fprint(w, `{"status": "unauthorized"}`)
+	}))
+	defer srv.Close()
+	c := newTestCanvasClient(srv)
+
+	if _, err := c.GetPages(context.Background(), c.url("/courses/42/assignments")); !errors.Is(err, ErrCanvasUnauthorized) {
+		t.Errorf("err = %v, want %v", err, ErrCanvasUnauthorized)
+	}
+	if requests != 1 {
+		t.Errorf("made %d requests, want 1", requests)
+	}
+}
+
+func TestGetPagesFromWorkers(t *testing.T) {
+	var mu sync.Mutex
+	requests := []string{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		requests = append(requests, r.URL.RequestURI())
+		mu.Unlock()
+		fmt.Fprint(w, `[{"id": 1}]`)
+	}))
+	defer srv.Close()
+	c := newTestCanvasClient(srv)
+	c.PerPage = 30
+
+	// Run with -race: nothing on this path may read settings from a worker
+	_, errs := runPool(context.Background(), 8, func(ctx context.Context, i int) ([]byte, error) {
+		return c.GetPages(ctx, c.url("/courses/%d/modules", i))
+	})
+	for _, err := range errs {
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, request := range requests {
+		if !strings.Contains(request, "per_page=30") {
+			t.Errorf("request %s doesn't use the client's per_page", request)
+		}
+	}
+}
+
+func TestNestedPoolsShareTheClientLimit(t *testing.T) {
+	var mu sync.Mutex
+	inFlight, most := 0, 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		inFlight++
+		if inFlight > most {
+			most = inFlight
+		}
+		mu.Unlock()
+		time.Sleep(5 * time.Millisecond)
+		mu.Lock()
+		inFlight--
+		mu.Unlock()
+		fmt.Fprint(w, `[{"id": 1}]`)
+	}))
+	defer srv.Close()
+	c := newTestCanvasClient(srv)
+	c.Concurrency = 3
+
+	// Courses times modules, like CollectCourseItems around GetAllAssignmentsByModule
+	_, errs := runPool(context.Background(), 4, func(ctx context.Context, course int) ([]error, error) {
+		_, errs := runPool(ctx, 4, func(ctx context.Context, module int) ([]byte, error) {
+			return c.GetPages(ctx, c.url("/courses/%d/modules/%d/items", course, module))
+		})
+		return errs, errors.Join(errs...)
+	})
+	if err := errors.Join(errs...); err != nil {
+		t.Fatal(err)
+	}
+	if most > c.Concurrency {
+		t.Errorf("%d requests were in flight at once, want at most %d", most, c.Concurrency)
+	}
+}
+
+func TestGetContextPagesBatches(t *testing.T) {
+	batches := [][]string{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("active_only") != "true" {
+			t.Errorf("request %s lost the active_only param", r.URL.RequestURI())
+		}
+		codes := r.URL.Query()["context_codes[]"]
+		batches = append(batches, codes)
+		items := []map[string]string{}
+		for _, code := range codes {
+			items = append(items, map[string]string{"context_code": code})
+		}
+		json.NewEncoder(w).Encode(items)
+	}))
+	defer srv.Close()
+	c := newTestCanvasClient(srv)
+
+	codes := []string{}
+	for i := 1; i <= 12; i++ {
+		codes = append(codes, fmt.Sprintf("course_%d", i))
+	}
+	query := url.Values{}
+	query.Set("active_only", "true")
+	announcements, err := getContextPages[canvas_announcement](context.Background(), c, "/announcements", query, codes)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(batches) != 2 || len(batches[0]) != canvasMaxContextCodes || len(batches[1]) != 2 {
+		t.Errorf("batches = %v, want 10 codes then 2", batches)
+	}
+	if len(announcements) != 12 || announcements[11].Context_Code != "course_12" {
+		t.Errorf("got %d announcements, want one per context", len(announcements))
+	}
+	if len(query["context_codes[]"]) != 0 {
+		t.Errorf("the caller's query was changed: %v", query)
+	}
+}
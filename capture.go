@@ -0,0 +1,121 @@
+package main
+
+import (
+	"crypto/sha1"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Raw Canvas responses can be captured to disk and replayed later, so the
+// decoders can be run (and debugged) without network access or a token.
+//
+//	<dir>/<canvas host>/<endpoint>[_<query>]/<timestamp>.json
+//
+// ex. captures/webcourses.ucf.edu/courses_1461901_modules/20241002T140502.123Z.json
+// or  captures/webcourses.ucf.edu/calendar_events_context_codes_course_1461901_type_event/...
+// Replay always reads the newest capture of an endpoint.
+
+const captureTimeFormat = "20060102T150405.000Z"
+
+var captureUnsafeChars = regexp.MustCompile(`[^A-Za-z0-9._-]+`)
+
+// Query params left out of capture folders, so captures still match when the
+// date window moves or the page size changes
+var captureIgnoredParams = map[string]bool{"start_date": true, "end_date": true, "per_page": true, "page": true}
+
+// Folder names longer than this get a hash of their query instead
+const captureMaxQueryLength = 200
+
+// Helper function to read the capture directory, capturing is off when it is empty
+func canvasCaptureDir() string {
+	return GetEnvVar("CANVAS_CAPTURE_DIR", "", "", "capture")
+}
+
+// Helper function to read the replay directory, replay is off when it is empty
+func canvasReplayDir() string {
+	return GetEnvVar("CANVAS_REPLAY_DIR", "", "", "replay")
+}
+
+// Helper function to turn a Canvas URL into the folder its captures live in.
+// Query params that pick what comes back (type, context_codes[], include[], ...)
+// are part of it, so ex. each batch of calendar contexts gets its own captures.
+func capturePath(dir, rawURL string) (string, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return "", err
+	}
+	endpoint := strings.TrimPrefix(u.Path, "/api/v1")
+	endpoint = captureUnsafeChars.ReplaceAllString(strings.Trim(endpoint, "/"), "_")
+	if endpoint == "" {
+		endpoint = "root"
+	}
+	if query := captureQuery(u.Query()); query != "" {
+		endpoint += "_" + query
+	}
+	return filepath.Join(dir, captureUnsafeChars.ReplaceAllString(u.Host, "_"), endpoint), nil
+}
+
+// Helper function to turn the query params that matter into part of a folder name
+func captureQuery(query url.Values) string {
+	keys := []string{}
+	for key := range query {
+		if !captureIgnoredParams[key] {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+
+	parts := []string{}
+	for _, key := range keys {
+		parts = append(parts, strings.TrimSuffix(key, "[]"))
+		parts = append(parts, query[key]...)
+	}
+	name := strings.Trim(captureUnsafeChars.ReplaceAllString(strings.Join(parts, "_"), "_"), "_")
+	if len(name) > captureMaxQueryLength {
+		return fmt.Sprintf("%x", sha1.Sum([]byte(name)))
+	}
+	return name
+}
+
+// Helper function to save a raw response body under the capture directory
+func writeCapture(dir, rawURL string, body []byte) error {
+	folder, err := capturePath(dir, rawURL)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(folder, 0755); err != nil {
+		return err
+	}
+	name := filepath.Join(folder, time.Now().UTC().Format(captureTimeFormat)+".json")
+	return os.WriteFile(name, body, 0644)
+}
+
+// Helper function to read the newest capture of an endpoint
+func readCapture(dir, rawURL string) ([]byte, error) {
+	folder, err := capturePath(dir, rawURL)
+	if err != nil {
+		return nil, err
+	}
+	entries, err := os.ReadDir(folder)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+
+	names := []string{}
+	for _, entry := range entries {
+		if !entry.IsDir() && strings.HasSuffix(entry.Name(), ".json") {
+			names = append(names, entry.Name())
+		}
+	}
+	if len(names) == 0 {
+		return nil, &CanvasError{Kind: ErrCanvasNotFound, URL: rawURL, Err: fmt.Errorf("no capture in %s", folder)}
+	}
+	sort.Strings(names)
+	return os.ReadFile(filepath.Join(folder, names[len(names)-1]))
+}
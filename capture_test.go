@@ -0,0 +1,82 @@
+package main
+
+import (
+	"fmt"
+	"net/url"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// Helper function to build a calendar events URL like GetCalendarEvents does
+func calendarEventsURL(eventType, start string, codes ...string) string {
+	query := url.Values{}
+	query.Set("type", eventType)
+	query.Set("start_date", start)
+	query.Set("end_date", "2024-11-01T00:00:00Z")
+	query.Set("per_page", "100")
+	for _, code := range codes {
+		query.Add("context_codes[]", code)
+	}
+	return "https://webcourses.ucf.edu/api/v1/calendar_events?" + query.Encode()
+}
+
+func TestCaptureKeepsQueriesApart(t *testing.T) {
+	dir := t.TempDir()
+	captures := map[string]string{
+		calendarEventsURL("event", "2024-10-01T00:00:00Z", "course_1", "course_2"):      `["events 1-2"]`,
+		calendarEventsURL("assignment", "2024-10-01T00:00:00Z", "course_1", "course_2"): `["assignments 1-2"]`,
+		calendarEventsURL("event", "2024-10-01T00:00:00Z", "course_3"):                  `["events 3"]`,
+	}
+	for rawURL, body := range captures {
+		if err := writeCapture(dir, rawURL, []byte(body)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for rawURL, want := range captures {
+		// The date window moves between the capture and the replay
+		replayURL := strings.Replace(rawURL, "2024-10-01", "2024-10-05", 1)
+		body, err := readCapture(dir, replayURL)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(body) != want {
+			t.Errorf("replaying %s read %s, want %s", replayURL, body, want)
+		}
+	}
+}
+
+func TestCapturePath(t *testing.T) {
+	tests := []struct {
+		url  string
+		want string
+	}{
+		{"https://webcourses.ucf.edu/api/v1/courses/1461901/modules?per_page=100", "courses_1461901_modules"},
+		{"https://webcourses.ucf.edu/api/v1/planner/items?start_date=2024-10-01T00%3A00%3A00Z&end_date=2024-11-01T00%3A00%3A00Z", "planner_items"},
+		{calendarEventsURL("event", "2024-10-01T00:00:00Z", "course_1461901", "user_42"), "calendar_events_context_codes_course_1461901_user_42_type_event"},
+		{"https://webcourses.ucf.edu/api/v1/courses/1461901/assignment_groups?include[]=assignments", "courses_1461901_assignment_groups_include_assignments"},
+	}
+	for _, tt := range tests {
+		got, err := capturePath("captures", tt.url)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if want := filepath.Join("captures", "webcourses.ucf.edu", tt.want); got != want {
+			t.Errorf("capturePath(%s) = %s, want %s", tt.url, got, want)
+		}
+	}
+
+	// A full batch of context codes still makes a usable folder name
+	codes := []string{}
+	for i := 0; i < 2*canvasMaxContextCodes; i++ {
+		codes = append(codes, fmt.Sprintf("course_%d", 1461900+i))
+	}
+	got, err := capturePath("captures", calendarEventsURL("event", "2024-10-01T00:00:00Z", codes...))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if name := filepath.Base(got); len(name) > 255 {
+		t.Errorf("folder name is %d long: %s", len(name), name)
+	}
+}
@@ -5,8 +5,11 @@ import (
 	"encoding/json"
 	"fmt"
 	"io/ioutil"
+	"math"
 	"net/http"
+	"sort"
 	"strings"
+	"time"
 )
 
 type ChatGPTRequest struct {
@@ -36,6 +39,68 @@ type ChatGPTResponse struct {
 	} `json:"choices"`
 }
 
+// The assignment data sent along with the prompt, one entry per item
+type ChatGPTItem struct {
+	Course    string  `json:"course"`
+	Type      string  `json:"type"`
+	Title     string  `json:"title"`
+	Due       string  `json:"due,omitempty"`
+	Start     string  `json:"start,omitempty"` // calendar events happen at a fixed time
+	End       string  `json:"end,omitempty"`
+	Location  string  `json:"location,omitempty"`
+	FixedTime bool    `json:"fixed_time,omitempty"`
+	Points    float64 `json:"points,omitempty"`
+	Details   string  `json:"details,omitempty"` // quiz time limit, attempts and lock times
+	Submitted bool    `json:"submitted"`
+
+	GradeShare  float64 `json:"grade_share_percent,omitempty"` // how much of the final grade it is worth
+	CourseGrade string  `json:"course_grade,omitempty"`        // my current grade in the course
+}
+
+// ChatGPTItems flattens every course's items into the ChatGPT payload, the items
+// worth the most of their final grade first
+func ChatGPTItems(groups []CourseItems) []ChatGPTItem {
+	items := []ChatGPTItem{}
+	for _, group := range groups {
+		// Undated items go last, without a due date, so they can fill free time
+		for _, item := range append(group.Items, group.Undated...) {
+			chatItem := ChatGPTItem{
+				Course:    group.Course.Name + sourceTag(item.Source),
+				Type:      item.KindLabel(),
+				Title:     item.Title,
+				Points:    item.Points,
+				Details:   item.QuizDetails(),
+				Submitted: item.Completed,
+
+				GradeShare:  math.Round(item.GradeShare*1000) / 10,
+				CourseGrade: group.Grade.Text(),
+			}
+			if item.Kind == ItemEvent {
+				chatItem.FixedTime = true
+				chatItem.Location = item.Location
+				if !item.Due.IsZero() {
+					chatItem.Start = formatTime(item.Due.Format(time.RFC3339))
+				}
+				if !item.End.IsZero() {
+					chatItem.End = formatTime(item.End.Format(time.RFC3339))
+				}
+			} else if !item.Due.IsZero() {
+				chatItem.Due = formatTime(item.Due.Format(time.RFC3339))
+			}
+			items = append(items, chatItem)
+		}
+	}
+	// Undated items stay last whatever they are worth
+	sort.SliceStable(items, func(i, j int) bool {
+		iDated, jDated := items[i].Due != "" || items[i].Start != "", items[j].Due != "" || items[j].Start != ""
+		if iDated != jDated {
+			return iDated
+		}
+		return items[i].GradeShare > items[j].GradeShare
+	})
+	return items
+}
+
 func chatGptQuery(notionData string, partialQuery string) string {
 	apiKey := GetEnvVar("CHATGPT_KEY")
 	url := "https://api.openai.com/v1/chat/completions"
@@ -75,7 +140,9 @@ func chatGptQuery(notionData string, partialQuery string) string {
 				-Task
 				replace task with each thing I have to do that day and the time.
 				Also add a fun history fact at the end of each day as well as a daily motivating quote and daily protestant christian bible verse.
-				Base your calendar off of the date September 2nd 2024 being a Monday as well and don't forget my quote, fact, and bible verse and to include any due dates of assignments from the inputted data.`,
+				Base your calendar off of the date September 2nd 2024 being a Monday as well and don't forget my quote, fact, and bible verse and to include any due dates of assignments from the inputted data.
+				Items with a larger grade_share_percent are worth more of their course's final grade, so give them more time and start them earlier, especially in courses where my course_grade is low.
+				Items marked fixed_time are calendar events such as exams and lab sessions that happen from start to end at the given location. Schedule them exactly at that time and plan everything else around them.`,
 			},
 			{
 				Role:    "user",
@@ -90,6 +157,9 @@ func chatGptQuery(notionData string, partialQuery string) string {
 		fmt.Println("Error marshaling request body:", err)
 		return ""
 	}
+	if writeReplayRequest("chatgpt_request", "POST", url, jsonBody) {
+		return ""
+	}
 
 	// Create a new HTTP request
 	req, err := http.NewRequest("POST", url, bytes.NewBuffer(jsonBody))
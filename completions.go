@@ -0,0 +1,87 @@
+package main
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/landerson7/chatgptnotionplanner/notionblock"
+)
+
+// TodoState is a to-do as it was left on a previous page
+type TodoState struct {
+	Text    string
+	URL     string // where the title links to, the item's Canvas page
+	Checked bool
+}
+
+// Helper function to read whether completed items are left off the page instead of shown checked
+func hideCompleted() bool {
+	return GetEnvVarBool("HIDE_COMPLETED", false, "", "hide-completed", "", "bool")
+}
+
+// ReadTodoStates reads the to-dos of the given pages, so their checked state can
+// be carried over before the pages are archived. Pages that can't be read are skipped.
+func ReadTodoStates(pageIDs []string) []TodoState {
+	states := []TodoState{}
+	for _, pageID := range pageIDs {
+		blocks, err := GetNotionBlockChildren(pageID)
+		if err != nil {
+			fmt.Println("Error reading to-dos of page "+pageID+":", err)
+			continue
+		}
+		for _, block := range blocks {
+			if block.ToDo == nil {
+				continue
+			}
+			state := TodoState{Text: notionblock.Content(block.ToDo.RichText), Checked: block.ToDo.Checked}
+			for _, text := range block.ToDo.RichText {
+				if text.Text.Link != nil {
+					state.URL = text.Text.Link.URL
+					break
+				}
+			}
+			states = append(states, state)
+		}
+	}
+	return states
+}
+
+// RecordTodoStates matches the to-dos of the previous pages to this run's items,
+// by the Canvas link of their title or else by their text, and records in the
+// history which ones I checked or unchecked
+func (h *History) RecordTodoStates(groups []CourseItems, states []TodoState, now time.Time) {
+	byURL := make(map[string]TodoState)
+	byText := make(map[string]TodoState)
+	for _, state := range states {
+		if state.URL != "" {
+			byURL[state.URL] = state
+		}
+		byText[state.Text] = state
+	}
+
+	for _, group := range groups {
+		for _, item := range append(group.Items, group.Undated...) {
+			state, ok := byURL[item.URL]
+			if !ok || item.URL == "" {
+				state, ok = byText[item.TodoText()]
+			}
+			if ok {
+				h.SetCompleted(ItemKey(item), state.Checked, now)
+			}
+		}
+	}
+}
+
+// Helper function to leave completed items off the page when HIDE_COMPLETED is set
+func visibleItems(items []PlannerItem) []PlannerItem {
+	if !hideCompleted() {
+		return items
+	}
+	visible := []PlannerItem{}
+	for _, item := range items {
+		if !item.Completed {
+			visible = append(visible, item)
+		}
+	}
+	return visible
+}
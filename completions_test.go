@@ -0,0 +1,59 @@
+package main
+
+import (
+	"os"
+	"testing"
+	"time"
+)
+
+func TestHideCompletedFlag(t *testing.T) {
+	isolateRun(t)
+	args := os.Args
+	defer func() { os.Args = args }()
+
+	for _, tt := range []struct {
+		args []string
+		want bool
+	}{
+		{[]string{"planner"}, false},
+		{[]string{"planner", "--hide-completed"}, true},
+		{[]string{"planner", "--hide-completed=true"}, true},
+		{[]string{"planner", "--hide-completed=false"}, false},
+	} {
+		os.Args = tt.args
+		if got := hideCompleted(); got != tt.want {
+			t.Errorf("hideCompleted() with %v = %v, want %v", tt.args, got, tt.want)
+		}
+	}
+}
+
+func TestRecordTodoStates(t *testing.T) {
+	now := time.Date(2024, 10, 1, 12, 0, 0, 0, time.UTC)
+	groups := []CourseItems{{Items: []PlannerItem{
+		{Kind: ItemAssignment, SourceID: 1, Title: "PA#1", URL: "https://canvas.test/courses/42/assignments/1"},
+		{Kind: ItemAssignment, SourceID: 2, Title: "PA#2"},
+		{Kind: ItemAssignment, SourceID: 3, Title: "PA#3", URL: "https://canvas.test/courses/42/assignments/3"},
+	}}}
+	states := []TodoState{
+		{URL: "https://canvas.test/courses/42/assignments/1", Text: "renamed since", Checked: true},
+		{Text: groups[0].Items[1].TodoText(), Checked: true},
+		{URL: "https://canvas.test/courses/42/assignments/3", Checked: false},
+	}
+
+	var history History
+	history.SetCompleted(ItemKey(groups[0].Items[2]), true, now.AddDate(0, 0, -1))
+	history.RecordTodoStates(groups, states, now)
+	history.ApplyCompletions(groups, now)
+
+	for i, want := range []bool{true, true, false} {
+		if got := groups[0].Items[i].Completed; got != want {
+			t.Errorf("%s completed = %v, want %v", groups[0].Items[i].Title, got, want)
+		}
+	}
+
+	// Completions are forgotten once they are too old to matter
+	history.ApplyCompletions(groups, now.Add(completionMaxAge+time.Hour))
+	if len(history.Completed) != 0 {
+		t.Errorf("old completions kept: %v", history.Completed)
+	}
+}
@@ -0,0 +1,218 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/spf13/viper"
+)
+
+// Fetch strategies a course can use in the courses config
+const (
+	StrategyAssignments = "assignments" // the course assignments endpoint
+	StrategyModules     = "modules"     // walk the course modules (ex. Geology)
+)
+
+const defaultCoursesConfigPath = "courses.yaml"
+
+type Course struct {
+	Name     string        `mapstructure:"name"`
+	CourseID int           `mapstructure:"course_id"`
+	Strategy string        `mapstructure:"strategy"`
+	Enabled  *bool         `mapstructure:"enabled"`
+	Source   string        `mapstructure:"-"` // name of the Canvas instance the course lives on
+	Client   *CanvasClient `mapstructure:"-"`
+}
+
+// Institution is one Canvas instance and the courses to pull from it
+type Institution struct {
+	Name     string   `mapstructure:"name"`      // source tag shown next to its items
+	BaseURL  string   `mapstructure:"base_url"`  // ex. https://webcourses.ucf.edu
+	TokenKey string   `mapstructure:"token_key"` // .env/environment key holding the API token
+	UserID   string   `mapstructure:"user_id"`   // defaults to "self"
+	Discover bool     `mapstructure:"discover"`  // also pull active enrollments from Canvas
+	Term     string   `mapstructure:"term"`      // only keep discovered courses in this term
+	Courses  []Course `mapstructure:"courses"`
+}
+
+// The top level of the config is the default institution, set up from the
+// CANVAS_* env vars; other Canvas instances go under institutions
+type CoursesConfig struct {
+	Institution  `mapstructure:",squash"`
+	Institutions []Institution `mapstructure:"institutions"`
+}
+
+// A course is enabled unless the config explicitly says otherwise
+func (c Course) IsEnabled() bool {
+	return c.Enabled == nil || *c.Enabled
+}
+
+// Helper function to get the config file path from .env, the environment or --courses
+func GetCoursesConfigPath() string {
+	return GetEnvVar("COURSES_CONFIG", defaultCoursesConfigPath, "", "courses")
+}
+
+// LoadCoursesConfig reads the courses config file (YAML or TOML, by extension)
+func LoadCoursesConfig(path string) (CoursesConfig, error) {
+	var config CoursesConfig
+
+	v := viper.New()
+	v.SetConfigFile(path)
+	if err := v.ReadInConfig(); err != nil {
+		return config, fmt.Errorf("error reading courses config %s: %w", path, err)
+	}
+	if err := v.Unmarshal(&config); err != nil {
+		return config, fmt.Errorf("error decoding courses config %s: %w", path, err)
+	}
+
+	for _, institution := range config.Institutions {
+		if institution.Name == "" || institution.BaseURL == "" || institution.TokenKey == "" {
+			return config, fmt.Errorf("institutions in %s need a name, base_url and token_key", path)
+		}
+	}
+	for _, institution := range config.All() {
+		for _, course := range institution.Courses {
+			if course.CourseID == 0 {
+				return config, fmt.Errorf("course %q in %s is missing a course_id", course.Name, path)
+			}
+			switch course.Strategy {
+			case "", StrategyAssignments, StrategyModules:
+			default:
+				return config, fmt.Errorf("course %q in %s has unknown strategy %q", course.Name, path, course.Strategy)
+			}
+		}
+	}
+
+	return config, nil
+}
+
+// All returns the default institution followed by the extra ones
+func (config CoursesConfig) All() []Institution {
+	return append([]Institution{config.Institution}, config.Institutions...)
+}
+
+// Client builds the Canvas client for an institution. The default one falls
+// back to the CANVAS_* env vars for anything the config leaves out.
+func (institution Institution) Client(isDefault bool) *CanvasClient {
+	if !isDefault {
+		return NewCanvasClient(institution.Name, institution.BaseURL, GetEnvVar(institution.TokenKey), institution.UserID)
+	}
+	client := NewDefaultCanvasClient()
+	if institution.Name != "" {
+		client.Name = institution.Name
+	}
+	if institution.BaseURL != "" {
+		client.BaseURL = strings.TrimRight(institution.BaseURL, "/")
+	}
+	if institution.TokenKey != "" {
+		client.Token = GetEnvVar(institution.TokenKey)
+	}
+	if institution.UserID != "" {
+		client.UserID = institution.UserID
+	}
+	return client
+}
+
+// LoadCourses returns the enabled courses of every institution, in config order.
+// When discovery is on, active Canvas enrollments not listed in the config are
+// added after them, and config entries override the name and strategy of the
+// matching discovered course. Discovery keeps the courses whose term includes now,
+// the run's time, so a replay finds the courses of the captured term.
+func LoadCourses(ctx context.Context, now time.Time) []Course {
+	config, err := LoadCoursesConfig(GetCoursesConfigPath())
+	if err != nil {
+		fmt.Println("Error loading courses config:", err)
+		return []Course{}
+	}
+
+	courses := []Course{}
+	for i, institution := range config.All() {
+		client := institution.Client(i == 0)
+		client.DisabledCourses = disabledCourses(institution.Courses)
+
+		var discovered []Course
+		if institution.Discover {
+			discovered, err = DiscoverCourses(ctx, client, institution.Term, now)
+			if err != nil {
+				fmt.Println("Error discovering courses, using the configured ones only:", err)
+			}
+		}
+
+		for _, course := range mergeCourses(institution.Courses, discovered) {
+			course.Source = client.Name
+			course.Client = client
+			courses = append(courses, course)
+		}
+	}
+	return courses
+}
+
+// DiscoverCourses turns the user's active enrollments on one Canvas instance into courses
+func DiscoverCourses(ctx context.Context, client *CanvasClient, term string, now time.Time) ([]Course, error) {
+	activeCourses, err := client.GetActiveCourses(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	courses := []Course{}
+	for _, canvasCourse := range activeCourses {
+		if !canvasCourse.InTerm(term, now) {
+			continue
+		}
+		name := canvasCourse.Course_Code
+		if name == "" {
+			name = canvasCourse.Name
+		}
+		courses = append(courses, Course{Name: name, CourseID: canvasCourse.Id})
+	}
+	return courses, nil
+}
+
+// Helper function to collect the ids of the courses the config turns off
+func disabledCourses(configured []Course) map[int]bool {
+	disabled := make(map[int]bool)
+	for _, course := range configured {
+		if !course.IsEnabled() {
+			disabled[course.CourseID] = true
+		}
+	}
+	return disabled
+}
+
+// Helper function to merge configured and discovered courses, dropping disabled ones
+func mergeCourses(configured, discovered []Course) []Course {
+	discoveredById := make(map[int]Course)
+	for _, course := range discovered {
+		discoveredById[course.CourseID] = course
+	}
+
+	courses := []Course{}
+	listed := make(map[int]bool)
+	for _, course := range configured {
+		listed[course.CourseID] = true
+		if !course.IsEnabled() {
+			continue
+		}
+		if course.Name == "" {
+			course.Name = discoveredById[course.CourseID].Name
+		}
+		courses = append(courses, course)
+	}
+	for _, course := range discovered {
+		if !listed[course.CourseID] {
+			courses = append(courses, course)
+		}
+	}
+
+	for i, course := range courses {
+		if course.Name == "" {
+			courses[i].Name = fmt.Sprintf("Course %d", course.CourseID)
+		}
+		if course.Strategy == "" {
+			courses[i].Strategy = StrategyAssignments
+		}
+	}
+	return courses
+}
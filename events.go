@@ -0,0 +1,196 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Calendar event ids are numbers, except on assignment events where Canvas
+// makes up strings like "assignment_987", so they are kept as text
+type calendarEventID string
+
+func (id *calendarEventID) UnmarshalJSON(data []byte) error {
+	var text string
+	if err := json.Unmarshal(data, &text); err == nil {
+		*id = calendarEventID(text)
+		return nil
+	}
+	var number json.Number
+	if err := json.Unmarshal(data, &number); err != nil {
+		return err
+	}
+	*id = calendarEventID(number.String())
+	return nil
+}
+
+type calendar_event struct {
+	Id                     calendarEventID `json:"id"`
+	Title                  string          `json:"title"`
+	Type                   string          `json:"type"` // "event" or "assignment"
+	Start_At               string          `json:"start_at"`
+	End_At                 string          `json:"end_at"`
+	All_Day                bool            `json:"all_day"`
+	Location_Name          string          `json:"location_name"`
+	Html_Url               string          `json:"html_url"`
+	Context_Code           string          `json:"context_code"`           // ex. "course_1461901" or "user_42"
+	Effective_Context_Code string          `json:"effective_context_code"` // the course a booked appointment slot belongs to
+	Appointment_Group_Id   int             `json:"appointment_group_id"`
+	Workflow_State         string          `json:"workflow_state"`
+	Assignment             *assignment_due `json:"assignment"` // only on type=assignment
+}
+
+type canvas_user struct {
+	Id   int    `json:"id"`
+	Name string `json:"name"`
+}
+
+// GetSelf returns the user the client's token belongs to
+func (c *CanvasClient) GetSelf(ctx context.Context) (canvas_user, error) {
+	url := c.url("/users/%s", c.UserID)
+
+	body, err := c.Get(ctx, url)
+	if err != nil {
+		return canvas_user{}, err
+	}
+
+	var result canvas_user
+	if err := json.Unmarshal(body, &result); err != nil {
+		return canvas_user{}, &CanvasError{Kind: ErrCanvasDecode, URL: url, Err: err}
+	}
+	return result, nil
+}
+
+// GetCalendarEvents returns the events of one type ("event" or "assignment") in
+// the given contexts between start and end
+func (c *CanvasClient) GetCalendarEvents(ctx context.Context, eventType string, contextCodes []string, start, end time.Time) ([]calendar_event, error) {
+	query := url.Values{}
+	query.Set("type", eventType)
+	query.Set("start_date", start.UTC().Format(time.RFC3339))
+	query.Set("end_date", end.UTC().Format(time.RFC3339))
+
+	result, err := getContextPages[calendar_event](ctx, c, "/calendar_events", query, contextCodes)
+	if err != nil {
+		return nil, err
+	}
+	fmt.Printf("Found %d calendar %ss\n", len(result), eventType)
+	return result, nil
+}
+
+// Helper function to find the course an event belongs to, 0 for personal events
+func (e calendar_event) courseID() int {
+	for _, code := range []string{e.Effective_Context_Code, e.Context_Code} {
+		if id, ok := strings.CutPrefix(code, "course_"); ok {
+			courseID, err := strconv.Atoi(id)
+			if err == nil {
+				return courseID
+			}
+		}
+	}
+	return 0
+}
+
+// Helper function to read the numeric id of a real event, 0 for made up ones
+func (e calendar_event) sourceID() int {
+	id, err := strconv.Atoi(string(e.Id))
+	if err != nil {
+		return 0
+	}
+	return id
+}
+
+// ToPlannerItem converts a calendar event into a fixed-time item
+func (e calendar_event) ToPlannerItem(course Course) PlannerItem {
+	if e.Type == "assignment" && e.Assignment != nil {
+		item := e.Assignment.ToPlannerItem(course)
+		item.Source = course.Source
+		return item
+	}
+	return PlannerItem{
+		Kind:     ItemEvent,
+		Course:   course.Name,
+		CourseID: course.CourseID,
+		Title:    e.Title,
+		Due:      parseCanvasTime(e.Start_At),
+		End:      parseCanvasTime(e.End_At),
+		AllDay:   e.All_Day,
+		Location: e.Location_Name,
+		URL:      e.Html_Url,
+		SourceID: e.sourceID(),
+		Source:   course.Source,
+	}
+}
+
+// Helper function to list the calendar contexts of some courses
+func courseContextCodes(courses []Course) []string {
+	codes := []string{}
+	for _, course := range courses {
+		codes = append(codes, fmt.Sprintf("course_%d", course.CourseID))
+	}
+	return codes
+}
+
+// AddCalendarEvents fetches the course events (exams, lab sessions, booked
+// appointment slots) and assignment events between start and end, once per
+// Canvas instance, and adds them to the matching course groups. Assignment
+// events already on the page as assignments aren't added twice.
+func AddCalendarEvents(ctx context.Context, groups []CourseItems, start, end time.Time) {
+	forEachClient(groups, func(client *CanvasClient, courses []Course, indexes []int) {
+		events, err := client.calendarEvents(ctx, courses, start, end)
+		for _, i := range indexes {
+			if err != nil {
+				groups[i].Errors = append(groups[i].Errors, FetchError{What: "calendar events", Err: err})
+				continue
+			}
+			groups[i].Items = mergeEvents(groups[i].Items, events, groups[i].Course, start, end)
+		}
+	})
+}
+
+// Helper function to fetch both kinds of events for some courses plus the
+// user's own calendar, where booked appointment slots live
+func (c *CanvasClient) calendarEvents(ctx context.Context, courses []Course, start, end time.Time) ([]calendar_event, error) {
+	codes := courseContextCodes(courses)
+	if me, err := c.GetSelf(ctx); err == nil {
+		codes = append(codes, fmt.Sprintf("user_%d", me.Id))
+	} else {
+		fmt.Println("Error fetching my Canvas user, skipping booked appointment slots:", err)
+	}
+
+	events, err := c.GetCalendarEvents(ctx, "event", codes, start, end)
+	if err != nil {
+		return nil, err
+	}
+	assignmentEvents, err := c.GetCalendarEvents(ctx, "assignment", courseContextCodes(courses), start, end)
+	if err != nil {
+		return nil, err
+	}
+	return append(events, assignmentEvents...), nil
+}
+
+// Helper function to add a course's events to its items
+func mergeEvents(items []PlannerItem, events []calendar_event, course Course, start, end time.Time) []PlannerItem {
+	assignments := make(map[int]bool)
+	for _, item := range items {
+		if item.AssignmentID != 0 {
+			assignments[item.AssignmentID] = true
+		}
+	}
+	for _, event := range events {
+		if event.courseID() != course.CourseID || event.Workflow_State == "deleted" {
+			continue
+		}
+		if event.Type == "assignment" && (event.Assignment == nil || assignments[event.Assignment.Id]) {
+			continue
+		}
+		item := event.ToPlannerItem(course)
+		if item.DueBetween(start, end) {
+			items = append(items, item)
+		}
+	}
+	return items
+}
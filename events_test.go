@@ -0,0 +1,66 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestDecodeAssignmentCalendarEvents(t *testing.T) {
+	var events []calendar_event
+	decodeFixture(t, "calendar_events_assignment.json", &events)
+	if len(events) != 1 {
+		t.Fatalf("got %d events, want 1", len(events))
+	}
+	event := events[0]
+	if event.Id != "assignment_8608634" || event.sourceID() != 0 {
+		t.Errorf("id = %q (source id %d), want assignment_8608634", event.Id, event.sourceID())
+	}
+	if event.Assignment == nil || event.Assignment.Id != 8608634 || event.courseID() != 1464092 {
+		t.Errorf("event = %+v, want assignment 8608634 in course 1464092", event)
+	}
+}
+
+func TestCalendarEventsOfBothTypes(t *testing.T) {
+	assignmentEvents, err := os.ReadFile(filepath.Join("testdata", "calendar_events_assignment.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.URL.Path == "/api/v1/users/self":
+			fmt.Fprint(w, `{"id": 99, "name": "Me"}`)
+		case r.URL.Path == "/api/v1/calendar_events" && r.URL.Query().Get("type") == "assignment":
+			w.Write(assignmentEvents)
+		case r.URL.Path == "/api/v1/calendar_events":
+			fmt.Fprint(w, `[{"id": 555, "title": "Midterm", "type": "event", "start_at": "2024-10-15T14:00:00Z", "end_at": "2024-10-15T15:15:00Z", "context_code": "course_1464092", "workflow_state": "active"}]`)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+	c := newTestCanvasClient(srv)
+
+	course := Course{Name: "OS", CourseID: 1464092, Client: c}
+	start := time.Date(2024, 10, 1, 0, 0, 0, 0, time.UTC)
+	end := start.AddDate(0, 2, 0)
+	groups := []CourseItems{{Course: course, Items: []PlannerItem{}}}
+	AddCalendarEvents(context.Background(), groups, start, end)
+
+	if len(groups[0].Errors) != 0 {
+		t.Fatalf("errors = %v", groups[0].Errors)
+	}
+	got := []string{}
+	for _, item := range groups[0].Items {
+		got = append(got, fmt.Sprintf("%s %s %d", item.Kind, item.Title, item.SourceID))
+	}
+	want := "[calendar_event Midterm 555 assignment PA#2 - Concurrent Hash Table (groups enabled) 8608634]"
+	if fmt.Sprint(got) != want {
+		t.Errorf("items = %v, want %s", got, want)
+	}
+}
@@ -0,0 +1,241 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"sort"
+)
+
+// Items worth at least this share of the final grade are listed as high impact
+const defaultHighImpactShare = 0.05
+
+type canvas_assignment_group struct {
+	Id           int              `json:"id"`
+	Name         string           `json:"name"`
+	Group_Weight float64          `json:"group_weight"` // percent, only used when the course applies weights
+	Assignments  []assignment_due `json:"assignments"`  // sent with include[]=assignments
+}
+
+// CourseGrade is my current standing in a course
+type CourseGrade struct {
+	Score  *float64 // percent, nil when the course hides totals
+	Letter string
+	Period string // current grading period, empty without grading periods
+}
+
+// GetCourse returns a single course
+func (c *CanvasClient) GetCourse(ctx context.Context, course int) (canvas_course, error) {
+	url := c.url("/courses/%d", course)
+
+	body, err := c.Get(ctx, url)
+	if err != nil {
+		return canvas_course{}, err
+	}
+
+	var result canvas_course
+	if err := json.Unmarshal(body, &result); err != nil {
+		return canvas_course{}, &CanvasError{Kind: ErrCanvasDecode, URL: url, Err: err}
+	}
+	return result, nil
+}
+
+// GetAssignmentGroups returns a course's assignment groups with their assignments
+func (c *CanvasClient) GetAssignmentGroups(ctx context.Context, course int) ([]canvas_assignment_group, error) {
+	url := c.url("/courses/%d/assignment_groups?include[]=assignments", course)
+
+	body, err := c.GetPages(ctx, url)
+	if err != nil {
+		return nil, err
+	}
+
+	var result []canvas_assignment_group
+	if err := json.Unmarshal(body, &result); err != nil {
+		return nil, &CanvasError{Kind: ErrCanvasDecode, URL: url, Err: err}
+	}
+	fmt.Printf("Found %d assignment groups\n", len(result))
+	return result, nil
+}
+
+// Helper function to read my current grade from my enrollments, preferring the
+// current grading period when the course has them
+func currentGrade(enrollments []canvas_enrollment) CourseGrade {
+	for _, enrollment := range enrollments {
+		if enrollment.Type != "StudentEnrollment" {
+			continue
+		}
+		if enrollment.Current_Period_Computed_Current_Score != nil {
+			return CourseGrade{
+				Score:  enrollment.Current_Period_Computed_Current_Score,
+				Letter: enrollment.Current_Period_Computed_Current_Grade,
+				Period: enrollment.Current_Grading_Period_Title,
+			}
+		}
+		return CourseGrade{Score: enrollment.Grades.Current_Score, Letter: enrollment.Grades.Current_Grade}
+	}
+	return CourseGrade{}
+}
+
+// Text is the grade as shown next to the course, ex. "91.5% (A-)"
+func (g CourseGrade) Text() string {
+	if g.Score == nil {
+		return ""
+	}
+	text := fmt.Sprintf("%.1f%%", *g.Score)
+	if g.Letter != "" {
+		text += " (" + g.Letter + ")"
+	}
+	if g.Period != "" {
+		text += " in " + g.Period
+	}
+	return text
+}
+
+// GradeShares works out which share of the final grade each assignment is worth,
+// keyed by assignment id. With weights an assignment gets its group's weight
+// split by points, without them every point counts the same. Like Canvas, the
+// weights of groups with nothing to grade are spread over the other groups, and
+// when no group has a weight at all, points are all there is to go by.
+// Drop rules aren't taken into account.
+func GradeShares(groups []canvas_assignment_group, weighted bool) map[int]float64 {
+	groupPoints := make(map[int]float64)
+	var totalPoints, totalWeight float64
+	for _, group := range groups {
+		for _, assignment := range group.Assignments {
+			if counts(assignment) {
+				groupPoints[group.Id] += assignment.Points_Possible
+			}
+		}
+		totalPoints += groupPoints[group.Id]
+		if groupPoints[group.Id] > 0 {
+			totalWeight += group.Group_Weight
+		}
+	}
+
+	shares := make(map[int]float64)
+	for _, group := range groups {
+		for _, assignment := range group.Assignments {
+			if !counts(assignment) {
+				continue
+			}
+			if weighted && totalWeight > 0 {
+				shares[assignment.Id] = group.Group_Weight / totalWeight * assignment.Points_Possible / groupPoints[group.Id]
+			} else if totalPoints > 0 {
+				shares[assignment.Id] = assignment.Points_Possible / totalPoints
+			}
+		}
+	}
+	return shares
+}
+
+// Helper function to check if an assignment counts toward the final grade
+func counts(a assignment_due) bool {
+	return a.Published && !a.Omit_From_Final_Grade && a.Points_Possible > 0
+}
+
+// Helper function to check if the course's weighting setting changes any share.
+// It doesn't with a single graded group, without weights, or with weights that
+// match the points, which saves fetching the course just to read the setting.
+func weightingMatters(groups []canvas_assignment_group) bool {
+	weighted, unweighted := GradeShares(groups, true), GradeShares(groups, false)
+	if len(weighted) != len(unweighted) {
+		return true
+	}
+	for id, share := range unweighted {
+		if math.Abs(weighted[id]-share) > 1e-9 {
+			return true
+		}
+	}
+	return false
+}
+
+// Helper function to fetch a course's grade and the grade shares of its assignments.
+// The enrollments are shared with the date resolution, and the course itself is
+// only fetched when its weighting setting makes a difference.
+func (c *CanvasClient) courseGrades(ctx context.Context, course int) (CourseGrade, map[int]float64, error) {
+	groups, err := c.GetAssignmentGroups(ctx, course)
+	if err != nil {
+		return CourseGrade{}, nil, err
+	}
+	weighted := false
+	if weightingMatters(groups) {
+		info, err := c.GetCourse(ctx, course)
+		if err != nil {
+			return CourseGrade{}, nil, err
+		}
+		weighted = info.Apply_Assignment_Group_Weights
+	}
+	shares := GradeShares(groups, weighted)
+
+	enrollments, err := c.myEnrollments(ctx, course)
+	if err != nil {
+		return CourseGrade{}, shares, err
+	}
+	return currentGrade(enrollments), shares, nil
+}
+
+// AddGrades attaches my current grade to every course and each item's share of
+// the final grade, then puts the items worth the most first. A course whose
+// grades can't be fetched keeps its items as they are.
+func AddGrades(ctx context.Context, groups []CourseItems) {
+	_, errs := runPool(ctx, len(groups), func(ctx context.Context, i int) (struct{}, error) {
+		group := &groups[i]
+		if group.Course.Client == nil {
+			return struct{}{}, nil
+		}
+		grade, shares, err := group.Course.Client.courseGrades(ctx, group.Course.CourseID)
+		group.Grade = grade
+		for _, items := range [][]PlannerItem{group.Items, group.Undated} {
+			for j := range items {
+				items[j].GradeShare = shares[items[j].AssignmentID]
+			}
+		}
+		sortByImpact(group.Items)
+		return struct{}{}, err
+	})
+	for i, err := range errs {
+		if err != nil {
+			fmt.Println("Error fetching grades for "+groups[i].Course.Name+":", err)
+		}
+	}
+}
+
+// Helper function to order items by their share of the final grade, then by due date
+func sortByImpact(items []PlannerItem) {
+	sort.SliceStable(items, func(i, j int) bool {
+		if items[i].GradeShare != items[j].GradeShare {
+			return items[i].GradeShare > items[j].GradeShare
+		}
+		return items[i].Due.Before(items[j].Due)
+	})
+}
+
+// Helper function to read from which share of the final grade an item is high impact
+func highImpactShare() float64 {
+	return float64(GetEnvVarInt64("HIGH_IMPACT_PERCENT", int64(defaultHighImpactShare*100), 0, 100, "", "high-impact")) / 100
+}
+
+// HighImpactItems lists the open items across every course worth at least
+// highImpactShare of their course's final grade, the biggest first
+func HighImpactItems(groups []CourseItems) []PlannerItem {
+	threshold := highImpactShare()
+	items := []PlannerItem{}
+	for _, group := range groups {
+		for _, item := range group.Items {
+			if item.GradeShare > 0 && item.GradeShare >= threshold && !item.Completed {
+				items = append(items, item)
+			}
+		}
+	}
+	sortByImpact(items)
+	return items
+}
+
+// GradeText describes how much the item is worth, ex. "worth 12.5% of the final grade"
+func (item PlannerItem) GradeText() string {
+	if item.GradeShare <= 0 {
+		return ""
+	}
+	return fmt.Sprintf("worth %.1f%% of the final grade", item.GradeShare*100)
+}
@@ -0,0 +1,82 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"testing"
+)
+
+func TestCourseGradesRequests(t *testing.T) {
+	tests := []struct {
+		name   string
+		groups string
+		want   string // requests besides the assignment groups
+		share  float64
+	}{
+		{
+			"single group",
+			`[{"id": 1, "group_weight": 100, "assignments": [{"id": 10, "points_possible": 50, "published": true}, {"id": 11, "points_possible": 150, "published": true}]}]`,
+			"/api/v1/courses/42/enrollments", 0.25,
+		},
+		{
+			"no weights",
+			`[{"id": 1, "assignments": [{"id": 10, "points_possible": 50, "published": true}]}, {"id": 2, "assignments": [{"id": 11, "points_possible": 150, "published": true}]}]`,
+			"/api/v1/courses/42/enrollments", 0.25,
+		},
+		{
+			"weights that change the shares",
+			`[{"id": 1, "group_weight": 60, "assignments": [{"id": 10, "points_possible": 50, "published": true}]}, {"id": 2, "group_weight": 40, "assignments": [{"id": 11, "points_possible": 150, "published": true}]}]`,
+			"/api/v1/courses/42 /api/v1/courses/42/enrollments", 0.6,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			requests := []string{}
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				requests = append(requests, r.URL.Path)
+				switch r.URL.Path {
+				case "/api/v1/courses/42":
+					fmt.Fprint(w, `{"id": 42, "apply_assignment_group_weights": true}`)
+				case "/api/v1/courses/42/assignment_groups":
+					fmt.Fprint(w, tt.groups)
+				case "/api/v1/courses/42/enrollments":
+					fmt.Fprint(w, `[{"user_id": 7, "course_section_id": 3, "type": "StudentEnrollment", "grades": {"current_score": 91.5, "current_grade": "A-"}}]`)
+				default:
+					http.NotFound(w, r)
+				}
+			}))
+			defer srv.Close()
+			c := newTestCanvasClient(srv)
+
+			// The date resolution reads the enrollments first, the grades reuse them
+			if me := c.dateContext(context.Background(), 42); !me.Known || !me.Sections[3] {
+				t.Fatalf("date context = %+v", me)
+			}
+			grade, shares, err := c.courseGrades(context.Background(), 42)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if grade.Text() != "91.5% (A-)" {
+				t.Errorf("grade = %q", grade.Text())
+			}
+			if shares[10] != tt.share {
+				t.Errorf("share of assignment 10 = %v, want %v", shares[10], tt.share)
+			}
+
+			others := []string{}
+			for _, request := range requests {
+				if !strings.HasSuffix(request, "/assignment_groups") {
+					others = append(others, request)
+				}
+			}
+			sort.Strings(others)
+			if got := strings.Join(others, " "); got != tt.want {
+				t.Errorf("requests = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
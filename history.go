@@ -0,0 +1,249 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+)
+
+// History is the local record kept between runs, by default in history.json
+type History struct {
+	Snapshot Snapshot `json:"snapshot"`
+	// Items I marked done in Notion, keyed by ItemKey, with when that was first seen.
+	// They stay done even while Canvas shows them unsubmitted.
+	Completed map[string]time.Time `json:"completed,omitempty"`
+}
+
+// Completions older than a semester are forgotten
+const completionMaxAge = 180 * 24 * time.Hour
+
+// Snapshot is every item one run fetched, keyed by ItemKey. That includes the
+// undated ones and the ones outside the window, so an item moving out of the
+// window shows up as a moved due date rather than as removed.
+type Snapshot struct {
+	TakenAt     time.Time              `json:"taken_at"`
+	WindowStart time.Time              `json:"window_start"`
+	WindowEnd   time.Time              `json:"window_end"`
+	Items       map[string]PlannerItem `json:"items"`
+}
+
+// Kinds of change reported in the "Changes since yesterday" section
+const (
+	ChangeNew     = "new"
+	ChangeDueDate = "due_date"
+	ChangeLocked  = "locked"
+	ChangeRemoved = "removed"
+)
+
+// Change is one difference between the previous snapshot and this run
+type Change struct {
+	Kind   string
+	Item   PlannerItem
+	OldDue time.Time // only set for ChangeDueDate
+}
+
+// Helper function to read where the history is kept
+func GetHistoryPath() string {
+	return GetEnvVar("HISTORY_FILE", "history.json", "", "history")
+}
+
+// LoadHistory reads the history file. A missing file is an empty history.
+func LoadHistory(path string) (History, error) {
+	var history History
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return history, nil
+	} else if err != nil {
+		return history, err
+	}
+	if err := json.Unmarshal(data, &history); err != nil {
+		return history, fmt.Errorf("error reading history %s: %w", path, err)
+	}
+	return history, nil
+}
+
+// Save writes the history through a temporary file, so a crash never leaves half a file behind
+func (h History) Save(path string) error {
+	data, err := json.MarshalIndent(h, "", "	")
+	if err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".history-*")
+	if err != nil {
+		return err
+	}
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// SetCompleted records that I marked an item done in Notion, or clears it when I unmarked it
+func (h *History) SetCompleted(key string, done bool, now time.Time) {
+	if !done {
+		delete(h.Completed, key)
+		return
+	}
+	if h.Completed == nil {
+		h.Completed = make(map[string]time.Time)
+	}
+	if _, ok := h.Completed[key]; !ok {
+		h.Completed[key] = now
+	}
+}
+
+// ApplyCompletions marks the items I marked done in Notion as completed, whatever
+// Canvas says about them, after dropping completions too old to matter
+func (h *History) ApplyCompletions(groups []CourseItems, now time.Time) {
+	for key, at := range h.Completed {
+		if now.Sub(at) > completionMaxAge {
+			delete(h.Completed, key)
+		}
+	}
+	for i := range groups {
+		for _, items := range [][]PlannerItem{groups[i].Items, groups[i].Undated} {
+			for j := range items {
+				if _, ok := h.Completed[ItemKey(items[j])]; ok {
+					items[j].Completed = true
+				}
+			}
+		}
+	}
+}
+
+// ItemKey identifies an item across runs by its Canvas instance, kind and Canvas id
+func ItemKey(item PlannerItem) string {
+	return fmt.Sprintf("%s/%s/%d", item.Source, item.Kind, item.SourceID)
+}
+
+// NewSnapshot records every item of this run, taken at the run's now, with the
+// page's window. Courses that failed to fetch keep their items from the previous
+// snapshot, so a Canvas hiccup isn't remembered as everything being removed.
+func NewSnapshot(groups []CourseItems, previous Snapshot, now, start, end time.Time) Snapshot {
+	snapshot := Snapshot{TakenAt: now, WindowStart: start, WindowEnd: end, Items: make(map[string]PlannerItem)}
+	for _, group := range groups {
+		for _, items := range [][]PlannerItem{group.Items, group.Undated, group.Outside} {
+			for _, item := range items {
+				snapshot.Items[ItemKey(item)] = item
+			}
+		}
+	}
+	for key, item := range previous.Items {
+		if _, ok := snapshot.Items[key]; !ok && failedCourse(groups, item) {
+			snapshot.Items[key] = item
+		}
+	}
+	return snapshot
+}
+
+// Helper function to check if the item is on the page of the snapshot's run,
+// either inside its window or in the undated section
+func (s Snapshot) onPage(item PlannerItem) bool {
+	return item.Due.IsZero() || item.DueBetween(s.WindowStart, s.WindowEnd)
+}
+
+// Helper function to check if the item's course had a failed fetch this run
+func failedCourse(groups []CourseItems, item PlannerItem) bool {
+	for _, group := range groups {
+		if group.Course.CourseID == item.CourseID && group.Course.Source == item.Source {
+			return len(group.Errors) > 0
+		}
+	}
+	return false
+}
+
+// DiffSnapshots lists what changed between two runs: new items first, then moved
+// due dates, newly locked items and removals. Only changes to items on today's
+// page are reported, plus due dates moved off it. An item counts as removed only
+// if it would still be on the page, so items simply falling out of the window
+// aren't reported, and neither are items only now coming into it.
+func DiffSnapshots(previous, current Snapshot) []Change {
+	if previous.Items == nil {
+		return nil // first run, nothing to compare with
+	}
+
+	var added, moved, locked, removed []Change
+	for _, key := range sortedKeys(current.Items) {
+		item := current.Items[key]
+		old, ok := previous.Items[key]
+		if !ok {
+			if current.onPage(item) && (item.Due.IsZero() || previous.WindowEnd.IsZero() || item.Due.Before(previous.WindowEnd)) {
+				added = append(added, Change{Kind: ChangeNew, Item: item})
+			}
+			continue
+		}
+		if !old.Due.Equal(item.Due) && (current.onPage(item) || current.onPage(old)) {
+			moved = append(moved, Change{Kind: ChangeDueDate, Item: item, OldDue: old.Due})
+		}
+		if item.Locked && !old.Locked && current.onPage(item) {
+			locked = append(locked, Change{Kind: ChangeLocked, Item: item})
+		}
+	}
+	for _, key := range sortedKeys(previous.Items) {
+		old := previous.Items[key]
+		if _, ok := current.Items[key]; !ok && current.onPage(old) {
+			removed = append(removed, Change{Kind: ChangeRemoved, Item: old})
+		}
+	}
+
+	changes := append(added, moved...)
+	changes = append(changes, locked...)
+	return append(changes, removed...)
+}
+
+// Helper function to walk a snapshot in a stable order
+func sortedKeys(items map[string]PlannerItem) []string {
+	keys := make([]string, 0, len(items))
+	for key := range items {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		a, b := items[keys[i]], items[keys[j]]
+		if a.Course != b.Course {
+			return a.Course < b.Course
+		}
+		if !a.Due.Equal(b.Due) {
+			return a.Due.Before(b.Due)
+		}
+		return keys[i] < keys[j]
+	})
+	return keys
+}
+
+// Text is the line shown for the change on the Notion page
+func (c Change) Text() string {
+	item := c.Item
+	name := item.KindLabel() + ": " + item.Title + sourceTag(item.Source)
+	switch c.Kind {
+	case ChangeNew:
+		text := "New in " + item.Course + ": " + name
+		if !item.Due.IsZero() {
+			text += " Due at: " + formatTime(item.Due.Format(time.RFC3339))
+		}
+		return text
+	case ChangeDueDate:
+		return "Due date moved in " + item.Course + ": " + name + " from " + dueText(c.OldDue) + " to " + dueText(item.Due)
+	case ChangeLocked:
+		return "Now locked in " + item.Course + ": " + name
+	case ChangeRemoved:
+		return "Removed from " + item.Course + ": " + name
+	}
+	return name
+}
+
+// Helper function to print a due date that may be missing
+func dueText(due time.Time) string {
+	if due.IsZero() {
+		return "no due date"
+	}
+	return formatTime(due.Format(time.RFC3339))
+}
@@ -0,0 +1,53 @@
+package main
+
+import (
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestDiffSnapshotsOutsideWindow(t *testing.T) {
+	yesterday := time.Date(2024, 9, 29, 12, 0, 0, 0, time.UTC)
+	today := yesterday.AddDate(0, 0, 1)
+	course := Course{Name: "OS", CourseID: 1}
+	item := func(id int, due time.Time) PlannerItem {
+		return PlannerItem{Kind: ItemAssignment, Course: "OS", CourseID: 1, Title: fmt.Sprint("PA#", id), SourceID: id, Due: due}
+	}
+	split := func(items ...PlannerItem) CourseItems {
+		group := CourseItems{Course: course}
+		start, end := today, today.AddDate(0, 1, 0)
+		group.Items = itemsDueBetween(items, start, end)
+		group.Undated = undatedItems(items)
+		group.Outside = itemsOutside(items, start, end)
+		return group
+	}
+
+	due := today.AddDate(0, 0, 10)
+	extended := today.AddDate(0, 2, 0)
+	previous := NewSnapshot([]CourseItems{split(item(1, due), item(2, due), item(3, time.Time{}), item(4, extended))}, Snapshot{}, yesterday, yesterday, yesterday.AddDate(0, 1, 0))
+	current := NewSnapshot([]CourseItems{split(
+		item(1, extended),    // extended past the window
+		item(2, time.Time{}), // due date taken off
+		item(3, time.Time{}), // still undated
+		item(4, extended),    // still outside the window, unchanged
+		item(5, time.Time{}), // new and undated
+	)}, previous, today, today, today.AddDate(0, 1, 0))
+
+	got := []string{}
+	for _, change := range DiffSnapshots(previous, current) {
+		got = append(got, change.Text())
+	}
+	want := []string{
+		"New in OS: Assignment: PA#5",
+		"Due date moved in OS: Assignment: PA#2 from 10/10/2024 @ 08:00AM EDT to no due date",
+		"Due date moved in OS: Assignment: PA#1 from 10/10/2024 @ 08:00AM EDT to 11/30/2024 @ 07:00AM EST",
+	}
+	if len(got) != len(want) {
+		t.Fatalf("changes = %q, want %q", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("change %d = %q, want %q", i, got[i], want[i])
+		}
+	}
+}
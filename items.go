@@ -0,0 +1,350 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Item kinds, named after the Canvas planner's plannable_type values
+const (
+	ItemAssignment   = "assignment"
+	ItemDiscussion   = "discussion_topic"
+	ItemQuiz         = "quiz"
+	ItemAnnouncement = "announcement"
+	ItemEvent        = "calendar_event"
+	ItemNote         = "planner_note"
+	ItemPage         = "wiki_page"
+)
+
+// PlannerItem is the one normalized shape every Canvas source is converted to
+// and every output (Notion, ChatGPT) is built from
+type PlannerItem struct {
+	Kind      string    `json:"kind"`
+	Course    string    `json:"course"`
+	CourseID  int       `json:"course_id"`
+	Title     string    `json:"title"`
+	Due       time.Time `json:"due"` // zero when the item has no date
+	LockAt    time.Time `json:"lock_at"`
+	UnlockAt  time.Time `json:"unlock_at"`
+	Points    float64   `json:"points"`
+	URL       string    `json:"url"`
+	Locked    bool      `json:"locked"`
+	Submitted bool      `json:"submitted"`
+	Completed bool      `json:"completed"` // submitted, or marked done in the Canvas planner
+	Dismissed bool      `json:"dismissed"` // hidden from the Canvas planner
+	SourceID  int       `json:"source_id"` // Canvas id of the assignment, topic, quiz, ...
+	Source    string    `json:"source"`    // name of the Canvas instance
+
+	AssignmentID int     `json:"assignment_id,omitempty"` // assignment behind a graded discussion or quiz
+	GradeShare   float64 `json:"grade_share,omitempty"`   // share of the final grade, 0.05 for 5%
+
+	// Calendar event details, Due holds the start
+	End      time.Time `json:"end,omitempty"`
+	AllDay   bool      `json:"all_day,omitempty"`
+	Location string    `json:"location,omitempty"`
+
+	// Quiz settings, see QuizDetails
+	TimeLimit       int `json:"time_limit,omitempty"`       // minutes, 0 for no limit
+	AllowedAttempts int `json:"allowed_attempts,omitempty"` // -1 for unlimited
+}
+
+// CourseItems is one course's section of the output
+type CourseItems struct {
+	Course  Course
+	Items   []PlannerItem
+	Undated []PlannerItem // no due or to-do date, so never inside a date window
+	Outside []PlannerItem // dated but outside the window, only kept for the history
+	Errors  []FetchError
+	Grade   CourseGrade
+
+	Announcements []Announcement // newest first, only filled for the daily page
+}
+
+// FetchError records which part of a course could not be fetched
+type FetchError struct {
+	What string // ex. "assignments", "discussions"
+	Err  error
+}
+
+// Helper function to parse an optional Canvas timestamp, zero when missing or bad
+func parseCanvasTime(value string) time.Time {
+	if value == "" {
+		return time.Time{}
+	}
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		fmt.Println("Error parsing time:", err)
+		return time.Time{}
+	}
+	return t
+}
+
+// ToPlannerItem converts an assignment from the assignments or module endpoints
+func (a assignment_due) ToPlannerItem(course Course) PlannerItem {
+	kind := ItemAssignment
+	if a.Is_Quiz_Assignment || a.Is_Quiz_Lti_Assignment {
+		kind = ItemQuiz
+	}
+	return PlannerItem{
+		Kind:      kind,
+		Course:    course.Name,
+		CourseID:  course.CourseID,
+		Title:     a.Name,
+		Due:       parseCanvasTime(firstNonEmpty(a.Due_At, a.Todo_Date)),
+		LockAt:    parseCanvasTime(a.Lock_At),
+		UnlockAt:  parseCanvasTime(a.Unlock_At),
+		Points:    a.Points_Possible,
+		URL:       a.Html_Url,
+		Locked:    a.Locked_For_User,
+		Submitted: a.Submitted(),
+		Completed: a.Submitted(),
+		SourceID:  a.Id,
+		Source:    a.Source,
+
+		AssignmentID: a.Id,
+	}
+}
+
+// ToPlannerItem converts a discussion topic, taking dates and submission from its assignment
+func (d discussion_due) ToPlannerItem(course Course) PlannerItem {
+	item := d.Assignment.ToPlannerItem(course)
+	item.Kind = ItemDiscussion
+	item.Title = d.Title
+	item.SourceID = d.Id
+	item.Source = d.Source
+	item.URL = d.Html_Url
+	item.Locked = item.Locked || d.Locked_For_User
+	if item.Due.IsZero() {
+		item.Due = parseCanvasTime(firstNonEmpty(d.Due_At, d.Todo_Date))
+	}
+	if item.LockAt.IsZero() {
+		item.LockAt = parseCanvasTime(d.Lock_At)
+	}
+	return item
+}
+
+// Check if the item is due inside [start, end). A zero end means no upper bound.
+func (item PlannerItem) DueBetween(start, end time.Time) bool {
+	if item.Due.IsZero() || !start.Before(item.Due) {
+		return false
+	}
+	return end.IsZero() || item.Due.Before(end)
+}
+
+// Label used in front of the title on to-dos, ex. "Assignment"
+func (item PlannerItem) KindLabel() string {
+	switch item.Kind {
+	case ItemAssignment:
+		return "Assignment"
+	case ItemDiscussion:
+		return "Discussion"
+	case ItemQuiz:
+		return "Quiz"
+	case ItemAnnouncement:
+		return "Announcement"
+	case ItemEvent:
+		return "Event"
+	case ItemNote:
+		return "Note"
+	case ItemPage:
+		return "Page"
+	}
+	return "To-do"
+}
+
+// TodoText is the one-line text used for the item on Notion to-dos
+func (item PlannerItem) TodoText() string {
+	text := item.KindLabel() + ": " + item.Title
+	if item.Kind == ItemEvent {
+		return text + item.EventTime() + sourceTag(item.Source)
+	}
+	if !item.Due.IsZero() {
+		text += " Due at: " + formatTime(item.Due.Format(time.RFC3339))
+	}
+	if details := item.QuizDetails(); details != "" {
+		text += " (" + details + ")"
+	}
+	if grade := item.GradeText(); grade != "" {
+		text += " (" + grade + ")"
+	}
+	return text + sourceTag(item.Source)
+}
+
+// EventTime describes when and where an event happens, ex.
+// " At: 10/15/2024 @ 09:00AM EDT until 10:15AM, HEC 101"
+func (item PlannerItem) EventTime() string {
+	if item.Due.IsZero() {
+		return ""
+	}
+	start := formatTime(item.Due.Format(time.RFC3339))
+	text := " At: " + start
+	if item.AllDay {
+		text = " On: " + FormatDate(item.Due)
+	} else if !item.End.IsZero() {
+		end := formatTime(item.End.Format(time.RFC3339))
+		// Only the time when it ends the same day
+		startDay, _, _ := strings.Cut(start, " @ ")
+		if endDay, endTime, ok := strings.Cut(end, " @ "); ok && endDay == startDay {
+			end = endTime
+		}
+		text += " until " + end
+	}
+	if item.Location != "" {
+		text += ", " + item.Location
+	}
+	return text
+}
+
+// Helper function to keep the items that have no date at all
+func undatedItems(items []PlannerItem) []PlannerItem {
+	result := []PlannerItem{}
+	for _, item := range items {
+		if item.Due.IsZero() {
+			result = append(result, item)
+		}
+	}
+	return result
+}
+
+// Helper function to keep the dated items that fall outside [start, end)
+func itemsOutside(items []PlannerItem, start, end time.Time) []PlannerItem {
+	result := []PlannerItem{}
+	for _, item := range items {
+		if !item.Due.IsZero() && !item.DueBetween(start, end) {
+			result = append(result, item)
+		}
+	}
+	return result
+}
+
+// Helper function to return the first value that is set
+func firstNonEmpty(values ...string) string {
+	for _, value := range values {
+		if value != "" {
+			return value
+		}
+	}
+	return ""
+}
+
+// Helper function to keep the items due inside [start, end)
+func itemsDueBetween(items []PlannerItem, start, end time.Time) []PlannerItem {
+	result := []PlannerItem{}
+	for _, item := range items {
+		if item.DueBetween(start, end) {
+			result = append(result, item)
+		}
+	}
+	return result
+}
+
+// GetItemsForCourse fetches a course's assignments and discussions through the
+// per-course endpoints and converts them. Graded discussions also show up as
+// assignments, so those are only kept once, as discussions.
+func GetItemsForCourse(ctx context.Context, course Course) CourseItems {
+	result := CourseItems{Course: course, Items: []PlannerItem{}}
+
+	// Fetched first, the module strategy maps quiz items through them
+	quizzes, err := GetQuizzesForCourse(ctx, course)
+	if err != nil {
+		fmt.Println("Error fetching quizzes for "+course.Name+":", err)
+		result.Errors = append(result.Errors, FetchError{What: "quizzes", Err: err})
+	}
+	assignments, err := GetAssignmentsForCourse(ctx, course, quizzes)
+	if err != nil {
+		fmt.Println("Error fetching assignments for "+course.Name+":", err)
+		result.Errors = append(result.Errors, FetchError{What: "assignments", Err: err})
+	}
+	discussions, err := GetDiscussionsForCourse(ctx, course)
+	if err != nil {
+		fmt.Println("Error fetching discussions for "+course.Name+":", err)
+		result.Errors = append(result.Errors, FetchError{What: "discussions", Err: err})
+	}
+
+	attachDiscussionAssignments(discussions, assignments)
+
+	discussionAssignments := make(map[int]bool)
+	for _, discussion := range discussions {
+		if discussion.Assignment.Id != 0 {
+			discussionAssignments[discussion.Assignment.Id] = true
+		}
+	}
+
+	for _, assignment := range assignments {
+		if !discussionAssignments[assignment.Id] {
+			result.Items = append(result.Items, assignment.ToPlannerItem(course))
+		}
+	}
+	for _, discussion := range discussions {
+		result.Items = append(result.Items, discussion.ToPlannerItem(course))
+	}
+	result.Items = mergeQuizzes(result.Items, quizzes, course)
+	return result
+}
+
+// CollectCourseItems gathers every course's items for [start, end), from the Canvas
+// planner when it is turned on and from the per-course endpoints otherwise.
+// Courses are fetched in parallel but come back in config order.
+func CollectCourseItems(ctx context.Context, courses []Course, start, end time.Time) []CourseItems {
+	if UsePlanner() {
+		groups, err := GetPlannerItemsForCourses(ctx, courses, start, end)
+		if err == nil {
+			AddGrades(ctx, groups)
+			return groups
+		}
+		fmt.Println("Error fetching planner items, falling back to course fetches:", err)
+	}
+
+	groups, errs := runPool(ctx, len(courses), func(ctx context.Context, i int) (CourseItems, error) {
+		group := GetItemsForCourse(ctx, courses[i])
+		group.Undated = undatedItems(group.Items)
+		group.Outside = itemsOutside(group.Items, start, end)
+		group.Items = itemsDueBetween(group.Items, start, end)
+		return group, nil
+	})
+	for i, err := range errs {
+		// Only courses skipped after a cancel get here, everything else is in the group
+		if err != nil {
+			groups[i] = CourseItems{Course: courses[i], Items: []PlannerItem{}, Errors: []FetchError{{What: "course", Err: err}}}
+		}
+	}
+
+	// Exams and lab sessions are often calendar events rather than assignments
+	AddCalendarEvents(ctx, groups, start, end)
+	AddGrades(ctx, groups)
+	return groups
+}
+
+// Helper function to call fn once per Canvas instance with the courses fetched
+// through it and the indexes of their groups, for the fetches that cover every
+// course of an instance in one go
+func forEachClient(groups []CourseItems, fn func(client *CanvasClient, courses []Course, indexes []int)) {
+	var clients []*CanvasClient
+	for _, group := range groups {
+		if group.Course.Client != nil && !containsClient(clients, group.Course.Client) {
+			clients = append(clients, group.Course.Client)
+		}
+	}
+
+	for _, client := range clients {
+		courses := []Course{}
+		indexes := []int{}
+		for i, group := range groups {
+			if group.Course.Client == client {
+				courses = append(courses, group.Course)
+				indexes = append(indexes, i)
+			}
+		}
+		fn(client, courses, indexes)
+	}
+}
+
+// Helper function to tag a to-do with the Canvas instance it came from
+func sourceTag(source string) string {
+	if source == "" {
+		return ""
+	}
+	return " [" + strings.TrimSpace(source) + "]"
+}
@@ -1,8 +1,11 @@
 package main
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
+	"os"
+	"os/signal"
 	"time"
 )
 
@@ -89,18 +92,54 @@ func main() {
 	//GetAllAssignments()
 
 	//Main call
-	ArchivePageByName(FormatDate(time.Now()) + " Assignments and Discussions Due Within a Month")
-	ArchivePageByName(FormatDate(time.Now().AddDate(0, 0, -1)) + " Assignments and Discussions Due Within a Month")
-	notionRequest := SendAllAssignmentsToOneNotionPage()
-	chatgptData, err := json.Marshal(notionRequest)
+	// Ctrl+C cancels the Canvas fetches still in flight instead of waiting them out
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	// "setup" creates or updates the Notion database instead of running the sync
+	if len(os.Args) > 1 && os.Args[1] == "setup" {
+		if err := SetupNotionDatabase(ctx); err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	// A replay runs as of its captures, see replay.go
+	now := RunTime()
+
+	var courseItems []CourseItems
+	if UseNotionDatabase() {
+		// Rows are updated in place, so there is no old page to archive
+		courseItems = SyncAllAssignmentsToNotionDatabase(ctx, now)
+	} else {
+		// Read what I checked off on yesterday's and today's pages before archiving
+		// them, today's last so it wins when both have the same item
+		previousPages := []string{}
+		for _, day := range []time.Time{now.AddDate(0, 0, -1), now} {
+			pageIDs, err := FindNotionPagesByName(FormatDate(day) + " Assignments and Discussions Due Within a Month")
+			if err != nil {
+				fmt.Println("Error searching for pages:", err)
+				continue
+			}
+			previousPages = append(previousPages, pageIDs...)
+		}
+		previousTodos := ReadTodoStates(previousPages)
+		for _, pageID := range previousPages {
+			DeleteNotionPage(pageID)
+		}
+		courseItems = SendAllAssignmentsToOneNotionPage(ctx, now, previousTodos)
+	}
+	chatgptData, err := json.Marshal(ChatGPTItems(courseItems))
 	if err != nil {
 		fmt.Println("error marshalling chatpgt json")
 	}
 
-	if time.Now().Weekday() == time.Monday {
+	// Replays always write the ChatGPT prompt, it costs nothing offline
+	if now.Weekday() == time.Monday || ReplayMode() {
 		response := generateWeeklySchedule(string(chatgptData))
 		fmt.Println(response)
-		sendTextToNotionPage(FormatDate(time.Now())+" ChatGPT Weekly Schedule", "ChatGPT generated weekly schedule", response)
+		sendTextToNotionPage(FormatDate(now)+" ChatGPT Weekly Schedule", "ChatGPT generated weekly schedule", response)
 	}
 
 	//End of main call
@@ -2,38 +2,23 @@ package main
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io/ioutil"
 	"net/http"
+	"strings"
 	"time"
-)
-
-type RichText struct {
-	Type string `json:"type"`
-	Text struct {
-		Content string `json:"content"`
-	} `json:"text"`
-	Annotations struct {
-		Bold bool `json:"bold"`
-	} `json:"annotations"`
-}
 
-type Paragraph struct {
-	RichText []RichText `json:"rich_text"`
-}
+	"github.com/landerson7/chatgptnotionplanner/notionblock"
+)
 
-type ToDo struct {
-	RichText []RichText `json:"rich_text"` // Add rich_text field here
-	Checked  bool       `json:"checked"`
-}
+// Notion accepts at most 100 children in one request
+const notionMaxChildren = 100
 
-type Block struct {
-	Object    string     `json:"object"`
-	Type      string     `json:"type"`
-	Paragraph *Paragraph `json:"paragraph,omitempty"`
-	ToDo      *ToDo      `json:"to_do,omitempty"`
-}
+// The page generated pages are created under, unless NOTION_PARENT_PAGE_ID says otherwise
+const notionParentPageID = "713ae619-b5cd-482f-a0c6-27b2fa1bf1dc"
 
 type Parent struct {
 	PageID string `json:"page_id"`
@@ -43,178 +28,54 @@ type NotionRequest struct {
 	Parent     Parent `json:"parent"`
 	Properties struct {
 		Title struct {
-			Title []RichText `json:"title"`
+			Title []notionblock.RichText `json:"title"`
 		} `json:"title"`
 	} `json:"properties"`
-	Children []Block `json:"children"`
+	Children []notionblock.Block `json:"children"`
 }
 
-func SendToNotion(course string, to_do []string) {
-	notionApiKey := GetEnvVar("NOTION_API")
-	url := "https://api.notion.com/v1/pages"
-
-	// Define the JSON structure using structs
+// Helper function to start a page request titled title under the parent page
+func newPageRequest(title string, children ...notionblock.Block) NotionRequest {
 	notionRequest := NotionRequest{
-		Parent: Parent{
-			PageID: "713ae619-b5cd-482f-a0c6-27b2fa1bf1dc",
-		},
-		Properties: struct {
-			Title struct {
-				Title []RichText `json:"title"`
-			} `json:"title"`
-		}{
-			Title: struct {
-				Title []RichText `json:"title"`
-			}{
-				Title: []RichText{
-					{
-						Type: "text",
-						Text: struct {
-							Content string `json:"content"`
-						}{
-							Content: course + " Assignments",
-						},
-					},
-				},
-			},
-		},
-		Children: []Block{
-			{
-				Object: "block",
-				Type:   "paragraph",
-				Paragraph: &Paragraph{
-					RichText: []RichText{
-						{
-							Type: "text",
-							Text: struct {
-								Content string `json:"content"`
-							}{
-								Content: "Geology course to-dos retrieved from Webcourses",
-							},
-						},
-					},
-				},
-			},
-		},
+		Parent:   Parent{PageID: NotionParentPageID()},
+		Children: append([]notionblock.Block{}, children...),
 	}
+	notionRequest.Properties.Title.Title = []notionblock.RichText{notionblock.Plain(title)}
+	return notionRequest
+}
+
+func SendToNotion(course string, to_do []string) {
+	notionRequest := newPageRequest(course+" Assignments",
+		notionblock.Paragraph(notionblock.Plain("Geology course to-dos retrieved from Webcourses")),
+	)
 
 	// Add each to-do item as a new Block in the Children array
 	for _, item := range to_do {
-		toDoBlock := Block{
-			Object: "block",
-			Type:   "to_do",
-			ToDo: &ToDo{
-				RichText: []RichText{
-					{
-						Type: "text",
-						Text: struct {
-							Content string `json:"content"`
-						}{
-							Content: item,
-						},
-					},
-				},
-				Checked: false,
-			},
-		}
-		notionRequest.Children = append(notionRequest.Children, toDoBlock)
-	}
-
-	sendData, err := json.Marshal(notionRequest)
-	if err != nil {
-		fmt.Println("Error marshaling JSON:", err)
-		return
-	}
-
-	req, err := http.NewRequest("POST", url, bytes.NewBuffer(sendData))
-	if err != nil {
-		fmt.Println("error creating request:", err)
-		return
-	}
-
-	req.Header.Add("Authorization", "Bearer "+notionApiKey)
-	req.Header.Set("Content-Type", "application/json")
-	req.Header.Set("Notion-Version", "2022-06-28")
-
-	client := &http.Client{}
-	resp, err := client.Do(req)
-	if err != nil {
-		fmt.Println("Error sending request: ", err)
-		return
+		notionRequest.Children = append(notionRequest.Children, notionblock.ToDo(false, notionblock.Plain(item)))
 	}
-	defer resp.Body.Close()
 
-	body, err := ioutil.ReadAll(resp.Body)
-	if err != nil {
-		fmt.Println("Error reading response body:", err)
-		return
+	if _, err := CreateNotionPage(notionRequest); err != nil {
+		fmt.Println("Error creating Notion page:", err)
+		reportIncompletePage(err)
 	}
-	fmt.Println("Raw response body:", string(body))
 }
 
-func SendAllAssignmentsToNotion() {
-	var courses = map[string]int{
-		"Geology":  1464091,
-		"Cinema":   1463455,
-		"CompComm": 1464602,
-		"E1Lab":    1465496,
-		"E1Lec":    1465493,
-		"OS":       1464092,
-	}
-
-	//canvasApiKey := GetEnvVar("CANVAS_API")
-	var assignments []assignment_due
-	var discussions []discussion_due
-	for courseName, course := range courses {
+func SendAllAssignmentsToNotion(ctx context.Context) {
+	courses := LoadCourses(ctx, RunTime())
 
-		assignments = GetAllAssignmentsByCourse(course)
-		discussions = GetDiscussionPostByCourse(course)
+	for _, course := range courses {
+		dt := RunTime()
+		group := GetItemsForCourse(ctx, course)
 
 		todos := []string{}
-		dt := time.Now()
-
-		if len(assignments) != 0 {
-			for _, discussion := range assignments {
-				// Parse the Due_At string into a time.Time object
-				dueAtTime, err := time.Parse(time.RFC3339, discussion.Due_At)
-				if err != nil {
-					fmt.Println("Error parsing time:", err)
-					continue
-				}
-
-				// Format the Due_At time
-				todo := formatTime(discussion.Due_At)
-				// Check if the current time is not before the due date
-				if dt.Before(dueAtTime) { // && !discussion.Locked_For_User {
-					fmt.Print("executed\n")
-					todo = "Assignment: " + discussion.Name + " Due at: " + todo
-					todos = append(todos, todo)
-				}
-			}
+		for _, item := range itemsDueBetween(group.Items, dt, time.Time{}) {
+			todos = append(todos, item.TodoText())
 		}
-
-		if len(discussions) != 0 {
-			for _, discussion := range discussions {
-				// Parse the Assignment's Due_At string into a time.Time object
-				dueAtTime, err := time.Parse(time.RFC3339, discussion.Assignment.Due_At)
-				if err != nil {
-					fmt.Println("Error parsing time:", err)
-					continue
-				}
-
-				// Format the Due_At time
-				todo := formatTime(discussion.Assignment.Due_At)
-				// Check if the current time is not before the due date
-				if dt.Before(dueAtTime) { // && !discussion.Assignment.Locked_For_User {
-					fmt.Print("executed\n")
-					todo = "Assignment: " + discussion.Title + " Due at: " + todo
-					todos = append(todos, todo)
-				}
-
-			}
+		for _, item := range undatedItems(group.Items) {
+			todos = append(todos, item.TodoText())
 		}
 
-		SendToNotion(courseName+" Assignments as of "+FormatDate(dt), todos)
+		SendToNotion(course.Name+" Assignments as of "+FormatDate(dt), todos)
 	}
 	//updateToDoList("cdf832e3-454f-47cf-ab04-d2d63d4a6e00", todos)
 }
@@ -380,170 +241,79 @@ func SendAllAssignmentsToOneNotionPage() {
 	fmt.Println("Raw response body:", string(body))
 }*/
 
-type Course struct {
-	Name     string
-	CourseID int
-}
+// SendAllAssignmentsToOneNotionPage builds today's page from every course's items
+// and returns those items so the other outputs can reuse them. previous are the
+// to-dos of the pages it replaces, whose checked state is carried over.
+func SendAllAssignmentsToOneNotionPage(ctx context.Context, now time.Time, previous []TodoState) []CourseItems {
+	courses := LoadCourses(ctx, now)
 
-func SendAllAssignmentsToOneNotionPage() NotionRequest {
-	courses := []Course{
-		{"Geology", 1461901},
-		{"Cinema", 1463455},
-		{"CompComm", 1464602},
-		{"E1Lab", 1465496},
-		{"E1Lec", 1465493},
-		{"OS", 1464092},
-	}
+	notionRequest := newPageRequest(FormatDate(now) + " Assignments and Discussions Due Within a Month")
 
-	notionApiKey := GetEnvVar("NOTION_API")
-	url := "https://api.notion.com/v1/pages"
+	oneMonthLater := now.AddDate(0, 1, 0)
 
-	// Initialize the Notion request with the parent and properties
-	notionRequest := NotionRequest{
-		Parent: Parent{
-			PageID: "713ae619-b5cd-482f-a0c6-27b2fa1bf1dc",
-		},
-		Properties: struct {
-			Title struct {
-				Title []RichText `json:"title"`
-			} `json:"title"`
-		}{
-			Title: struct {
-				Title []RichText `json:"title"`
-			}{
-				Title: []RichText{
-					{
-						Type: "text",
-						Text: struct {
-							Content string `json:"content"`
-						}{
-							Content: FormatDate(time.Now()) + " Assignments and Discussions Due Within a Month",
-						},
-					},
-				},
-			},
-		},
-		Children: []Block{},
+	groups := CollectCourseItems(ctx, courses, now, oneMonthLater)
+
+	// Diff against the last run and put what changed at the top of the page
+	historyPath := GetHistoryPath()
+	history, historyErr := LoadHistory(historyPath)
+	if historyErr != nil {
+		fmt.Println("Error loading history, it won't be saved over this run:", historyErr)
 	}
 
-	now := time.Now()
-	oneMonthLater := now.AddDate(0, 1, 0)
+	// What I checked off in Notion stays checked, even before Canvas knows about it
+	history.RecordTodoStates(groups, previous, now)
+	history.ApplyCompletions(groups, now)
 
-	for _, course := range courses {
-		assignments := GetAllAssignmentsByCourse(course.CourseID)
-		discussions := GetDiscussionPostByCourse(course.CourseID)
-		if course.Name == "Geology" {
-			assignments = GetAllAssignmentsByModule()
-		}
-		// Add a paragraph block for each course
-		notionRequest.Children = append(notionRequest.Children, Block{
-			Object: "block",
-			Type:   "paragraph",
-			Paragraph: &Paragraph{
-				RichText: []RichText{
-					{
-						Type: "text",
-						Text: struct {
-							Content string `json:"content"`
-						}{
-							Content: course.Name + " Assignments and Discussions",
-						},
-						Annotations: struct {
-							Bold bool `json:"bold"`
-						}{
-							Bold: true,
-						},
-					},
-				},
-			},
-		})
+	snapshot := NewSnapshot(groups, history.Snapshot, now, now, oneMonthLater)
+	if history.Snapshot.Items != nil {
+		notionRequest.Children = append(notionRequest.Children, changesBlocks(DiffSnapshots(history.Snapshot, snapshot))...)
+	}
 
-		todos := []string{}
+	// The work worth the most goes before the per-course lists
+	notionRequest.Children = append(notionRequest.Children, highImpactBlocks(HighImpactItems(groups))...)
 
-		if len(assignments) != 0 {
-			for _, assignment := range assignments {
-				dueAtTime, err := time.Parse(time.RFC3339, assignment.Due_At)
-				if err != nil {
-					fmt.Println("Error parsing time:", err)
-					continue
-				}
-				if now.Before(dueAtTime) && dueAtTime.Before(oneMonthLater) && !assignment.Has_Submitted_Submissions {
-					todo := "Assignment: " + assignment.Name + " Due at: " + formatTime(assignment.Due_At)
-					todos = append(todos, todo)
-				}
-			}
-		}
+	// Fetched after the snapshot, so a failed announcements fetch doesn't count as a failed course
+	AddAnnouncements(ctx, groups, now)
+	notionRequest.Children = append(notionRequest.Children, announcementBlocks(groups)...)
+	if len(notionRequest.Children) > 0 {
+		notionRequest.Children = append(notionRequest.Children, notionblock.Divider())
+	}
 
-		if len(discussions) != 0 {
-			for _, discussion := range discussions {
-				dueAtTime, err := time.Parse(time.RFC3339, discussion.Assignment.Due_At)
-				fmt.Println(course.Name + discussion.Title + discussion.Assignment.Due_At)
-				if err != nil {
-					fmt.Println("Error parsing time:", err)
-					continue
-				}
-				if now.Before(dueAtTime) && dueAtTime.Before(oneMonthLater) && !discussion.Assignment.Has_Submitted_Submissions {
-					todo := "Discussion: " + discussion.Title + " Due at: " + formatTime(discussion.Assignment.Due_At)
-					fmt.Println("discussion: " + todo)
-					todos = append(todos, todo)
-				}
-			}
+	for _, group := range groups {
+		notionRequest.Children = append(notionRequest.Children, courseHeading(group))
+
+		// Flag a failed fetch on the page instead of leaving an empty heading
+		for _, fetchErr := range group.Errors {
+			notionRequest.Children = append(notionRequest.Children, fetchWarningBlock(fetchErr.What, fetchErr.Err))
 		}
 
 		// Add each to-do item as a new Block in the Children array
-		for _, item := range todos {
-			toDoBlock := Block{
-				Object: "block",
-				Type:   "to_do",
-				ToDo: &ToDo{
-					RichText: []RichText{
-						{
-							Type: "text",
-							Text: struct {
-								Content string `json:"content"`
-							}{
-								Content: item,
-							},
-						},
-					},
-					Checked: false,
-				},
-			}
-			notionRequest.Children = append(notionRequest.Children, toDoBlock)
+		for _, item := range visibleItems(group.Items) {
+			notionRequest.Children = append(notionRequest.Children, todoBlock(item))
 		}
 	}
 
-	sendData, err := json.Marshal(notionRequest)
-	if err != nil {
-		fmt.Println("Error marshaling JSON:", err)
-		return notionRequest
-	}
+	// Items without any date can't be placed in the month, so they get their own section
+	notionRequest.Children = append(notionRequest.Children, undatedBlocks(groups)...)
 
-	req, err := http.NewRequest("POST", url, bytes.NewBuffer(sendData))
+	pageID, err := CreateNotionPage(notionRequest)
 	if err != nil {
-		fmt.Println("error creating request:", err)
-		return notionRequest
-	}
-
-	req.Header.Add("Authorization", "Bearer "+notionApiKey)
-	req.Header.Set("Content-Type", "application/json")
-	req.Header.Set("Notion-Version", "2022-06-28")
-
-	client := &http.Client{}
-	resp, err := client.Do(req)
-	if err != nil {
-		fmt.Println("Error sending request: ", err)
-		return notionRequest
-	}
-	defer resp.Body.Close()
-
-	body, err := ioutil.ReadAll(resp.Body)
-	if err != nil {
-		fmt.Println("Error reading response body:", err)
-		return notionRequest
+		fmt.Println("Error creating Notion page:", err)
+		reportIncompletePage(err)
+	} else if pageID != "" {
+		// Only remember this run's items once its page is actually in Notion
+		history.Snapshot = snapshot
+	}
+
+	// The completions read off the old pages are kept even if the new page failed,
+	// those pages are archived already. A history that failed to load is left alone,
+	// saving would replace every recorded completion with just this run's.
+	if !ReplayMode() && historyErr == nil {
+		if err := history.Save(historyPath); err != nil {
+			fmt.Println("Error saving history:", err)
+		}
 	}
-	fmt.Println("Raw response body:", string(body))
-	return notionRequest
+	return groups
 }
 
 func DeleteNotionPage(pageID string) {
@@ -559,6 +329,9 @@ func DeleteNotionPage(pageID string) {
 		fmt.Println("Error marshaling JSON:", err)
 		return
 	}
+	if writeReplayRequest("notion_archive_page", "PATCH", url, sendData) {
+		return
+	}
 
 	req, err := http.NewRequest("PATCH", url, bytes.NewBuffer(sendData))
 	if err != nil {
@@ -603,109 +376,19 @@ type NotionSearchResponse struct {
 }
 
 func ArchivePageByName(pageName string) {
-	notionApiKey := GetEnvVar("NOTION_API")
-	searchURL := "https://api.notion.com/v1/search"
-
-	// Create the search request
-	searchRequest := NotionSearchRequest{
-		Query: pageName,
-	}
-	searchRequest.Filter.Value = "page"
-	searchRequest.Filter.Property = "object"
-
-	// Marshal the search request to JSON
-	searchData, err := json.Marshal(searchRequest)
-	if err != nil {
-		fmt.Println("Error marshaling search request:", err)
-		return
-	}
-
-	// Send the search request
-	req, err := http.NewRequest("POST", searchURL, bytes.NewBuffer(searchData))
+	pageIDs, err := FindNotionPagesByName(pageName)
 	if err != nil {
-		fmt.Println("Error creating search request:", err)
+		fmt.Println("Error searching for pages:", err)
 		return
 	}
-
-	req.Header.Add("Authorization", "Bearer "+notionApiKey)
-	req.Header.Set("Content-Type", "application/json")
-	req.Header.Set("Notion-Version", "2022-06-28")
-
-	client := &http.Client{}
-	resp, err := client.Do(req)
-	if err != nil {
-		fmt.Println("Error sending search request:", err)
-		return
-	}
-	defer resp.Body.Close()
-
-	body, err := ioutil.ReadAll(resp.Body)
-	if err != nil {
-		fmt.Println("Error reading search response:", err)
-		return
-	}
-
-	// Parse the search response
-	var searchResponse NotionSearchResponse
-	err = json.Unmarshal(body, &searchResponse)
-	if err != nil {
-		fmt.Println("Error unmarshaling search response:", err)
-		return
-	}
-
-	if len(searchResponse.Results) == 0 {
+	if len(pageIDs) == 0 {
 		fmt.Println("No pages found with the specified name")
 		return
 	}
 
-	// Archive only the page that matches the exact name
-	for _, result := range searchResponse.Results {
-		pageID := result.ID
-
-		// Fetch the page details to confirm the exact name match
-		pageDetails, err := getPageDetails(pageID)
-		if err != nil {
-			fmt.Println("Error fetching page details:", err)
-			continue
-		}
-
-		if pageDetails.Properties.Title.Title[0].Text.Content == pageName {
-			archiveURL := "https://api.notion.com/v1/pages/" + pageID
-
-			// Create the request body to archive the page
-			bodyData := map[string]bool{
-				"archived": true,
-			}
-			sendData, err := json.Marshal(bodyData)
-			if err != nil {
-				fmt.Println("Error marshaling archive request:", err)
-				continue
-			}
-
-			req, err := http.NewRequest("PATCH", archiveURL, bytes.NewBuffer(sendData))
-			if err != nil {
-				fmt.Println("Error creating archive request:", err)
-				continue
-			}
-
-			req.Header.Add("Authorization", "Bearer "+notionApiKey)
-			req.Header.Set("Content-Type", "application/json")
-			req.Header.Set("Notion-Version", "2022-06-28")
-
-			resp, err := client.Do(req)
-			if err != nil {
-				fmt.Println("Error sending archive request: ", err)
-				continue
-			}
-			defer resp.Body.Close()
-
-			archiveBody, err := ioutil.ReadAll(resp.Body)
-			if err != nil {
-				fmt.Println("Error reading archive response body:", err)
-				continue
-			}
-			fmt.Println("Archived page response:", string(archiveBody))
-		}
+	// Archive only the pages that match the exact name
+	for _, pageID := range pageIDs {
+		DeleteNotionPage(pageID)
 	}
 }
 
@@ -744,117 +427,187 @@ func getPageDetails(pageID string) (*NotionRequest, error) {
 	return &pageDetails, nil
 }
 func sendTextToNotionPage(pageName, pageDescription, pageParagraph string) {
-	notionApiKey := GetEnvVar("NOTION_API")
-	url := "https://api.notion.com/v1/pages"
-
 	// Split the pageParagraph into chunks of 2000 characters or less
-	chunks := splitIntoChunks(pageParagraph, 2000)
+	chunks := splitIntoChunks(pageParagraph, notionblock.MaxTextLength)
 
-	// Define the JSON structure using structs
-	notionRequest := NotionRequest{
-		Parent: Parent{
-			PageID: "713ae619-b5cd-482f-a0c6-27b2fa1bf1dc",
-		},
-		Properties: struct {
-			Title struct {
-				Title []RichText `json:"title"`
-			} `json:"title"`
-		}{
-			Title: struct {
-				Title []RichText `json:"title"`
-			}{
-				Title: []RichText{
-					{
-						Type: "text",
-						Text: struct {
-							Content string `json:"content"`
-						}{
-							Content: pageName,
-						},
-					},
-				},
-			},
-		},
-		Children: []Block{
-			{
-				Object: "block",
-				Type:   "paragraph",
-				Paragraph: &Paragraph{
-					RichText: []RichText{
-						{
-							Type: "text",
-							Text: struct {
-								Content string `json:"content"`
-							}{
-								Content: pageDescription,
-							},
-							Annotations: struct {
-								Bold bool `json:"bold"`
-							}{
-								Bold: true,
-							},
-						},
-					},
-				},
-			},
-		},
-	}
+	notionRequest := newPageRequest(pageName, notionblock.Paragraph(notionblock.Plain(pageDescription).Bold()))
 
 	// Add each chunk as a separate paragraph block
 	for _, chunk := range chunks {
-		notionRequest.Children = append(notionRequest.Children, Block{
-			Object: "block",
-			Type:   "paragraph",
-			Paragraph: &Paragraph{
-				RichText: []RichText{
-					{
-						Type: "text",
-						Text: struct {
-							Content string `json:"content"`
-						}{
-							Content: chunk,
-						},
-					},
-				},
-			},
-		})
+		notionRequest.Children = append(notionRequest.Children, notionblock.Paragraph(notionblock.Plain(chunk)))
 	}
 
 	// Marshal the request body to JSON
-	sendData, err := json.Marshal(notionRequest)
-	if err != nil {
-		fmt.Println("Error marshaling JSON:", err)
-		return
+	if _, err := CreateNotionPage(notionRequest); err != nil {
+		fmt.Println("Error creating Notion page:", err)
+		reportIncompletePage(err)
 	}
+}
 
-	// Create a new HTTP request
-	req, err := http.NewRequest("POST", url, bytes.NewBuffer(sendData))
-	if err != nil {
-		fmt.Println("Error creating request:", err)
-		return
+// Helper function to build the warning shown under a course whose Canvas fetch failed
+func fetchWarningBlock(what string, err error) notionblock.Block {
+	reason := "Canvas returned an error"
+	switch {
+	case errors.Is(err, ErrCanvasUnauthorized):
+		reason = "the Canvas token is missing, expired or not allowed"
+	case errors.Is(err, ErrCanvasNotFound):
+		reason = "Canvas could not find the course"
+	case errors.Is(err, ErrCanvasDisabled):
+		reason = "the course has that page turned off"
+	case errors.Is(err, ErrCanvasRateLimited):
+		reason = "Canvas rate limited the request"
+	case errors.Is(err, ErrCanvasDecode):
+		reason = "the Canvas response could not be read"
+	}
+
+	return notionblock.Callout("⚠️", notionblock.YellowBackground,
+		notionblock.Plain("Could not fetch "+what+": ").Bold(),
+		notionblock.Plain(reason+". This section may be incomplete."),
+	)
+}
+
+// Helper function to build the "Changes since yesterday" section
+func changesBlocks(changes []Change) []notionblock.Block {
+	blocks := []notionblock.Block{notionblock.Heading2(notionblock.Plain("Changes since yesterday"))}
+	if len(changes) == 0 {
+		return append(blocks, notionblock.Paragraph(notionblock.Plain("Nothing changed since the last run.").Italic()))
+	}
+	for _, change := range changes {
+		text := notionblock.Plain(change.Text())
+		if change.Kind == ChangeRemoved {
+			text = text.Color(notionblock.Gray)
+		}
+		blocks = append(blocks, notionblock.BulletedItem(text))
 	}
+	return blocks
+}
 
-	// Add headers
-	req.Header.Add("Authorization", "Bearer "+notionApiKey)
-	req.Header.Set("Content-Type", "application/json")
-	req.Header.Set("Notion-Version", "2022-06-28")
+// Helper function to build an item's text with its title linking to Canvas,
+// ex. "Assignment: " + "Lab 3" (linked) + " Due at: ..."
+func itemRichText(item PlannerItem) []notionblock.RichText {
+	label := item.KindLabel() + ": "
+	rest := strings.TrimPrefix(item.TodoText(), label+item.Title)
+	text := []notionblock.RichText{notionblock.Plain(label), notionblock.Plain(item.Title).Link(item.URL)}
+	if rest != "" {
+		text = append(text, notionblock.Plain(rest))
+	}
+	return text
+}
 
-	// Send the request
-	client := &http.Client{}
-	resp, err := client.Do(req)
-	if err != nil {
-		fmt.Println("Error sending request: ", err)
-		return
+// Helper function to build the to-do block of an item, checked when it's done
+func todoBlock(item PlannerItem) notionblock.Block {
+	return notionblock.ToDo(item.Completed, itemRichText(item)...)
+}
+
+// Helper function to build the heading of a course
+func courseHeading(group CourseItems) notionblock.Block {
+	text := []notionblock.RichText{notionblock.Plain(group.Course.Name + " Assignments and Discussions")}
+	if details := undatedCount(len(group.Undated)) + gradeSuffix(group.Grade); details != "" {
+		text = append(text, notionblock.Plain(details).Color(notionblock.Gray))
 	}
-	defer resp.Body.Close()
+	return notionblock.Heading2(text...)
+}
 
-	// Read and print the response body
-	body, err := ioutil.ReadAll(resp.Body)
-	if err != nil {
-		fmt.Println("Error reading response body:", err)
-		return
+// Helper function to build the "No due date" section, empty when every item has a date
+func undatedBlocks(groups []CourseItems) []notionblock.Block {
+	blocks := []notionblock.Block{}
+	for _, group := range groups {
+		if len(group.Undated) == 0 {
+			continue
+		}
+		if len(blocks) == 0 {
+			blocks = append(blocks, notionblock.Heading2(notionblock.Plain("No due date")))
+		}
+		blocks = append(blocks, notionblock.Heading3(notionblock.Plain(group.Course.Name+undatedCount(len(group.Undated)))))
+		for _, item := range visibleItems(group.Undated) {
+			blocks = append(blocks, todoBlock(item))
+		}
 	}
-	fmt.Println("Raw response body:", string(body))
+	return blocks
+}
+
+// Helper function to build the announcements digest, one toggle per announcement
+// that opens to its message. Empty when nothing was posted.
+func announcementBlocks(groups []CourseItems) []notionblock.Block {
+	blocks := []notionblock.Block{}
+	for _, group := range groups {
+		if len(group.Announcements) == 0 {
+			continue
+		}
+		if len(blocks) == 0 {
+			blocks = append(blocks, notionblock.Heading2(notionblock.Plain("Announcements")))
+		}
+		blocks = append(blocks, notionblock.Heading3(notionblock.Plain(group.Course.Name)))
+		for _, announcement := range group.Announcements {
+			blocks = append(blocks, announcementBlock(announcement))
+		}
+	}
+	return blocks
+}
+
+// Helper function to build the toggle of one announcement
+func announcementBlock(announcement Announcement) notionblock.Block {
+	text := []notionblock.RichText{notionblock.Plain(announcement.Title).Link(announcement.URL).Bold()}
+	if !announcement.PostedAt.IsZero() {
+		posted := " posted " + formatTime(announcement.PostedAt.Format(time.RFC3339))
+		if announcement.Author != "" {
+			posted += " by " + announcement.Author
+		}
+		text = append(text, notionblock.Plain(posted).Color(notionblock.Gray))
+	}
+
+	children := []notionblock.Block{}
+	for _, paragraph := range strings.Split(announcement.Text, "\n\n") {
+		// Notion caps a text block at 2000 characters and a toggle at 100 children
+		for _, chunk := range splitIntoChunks(paragraph, notionblock.MaxTextLength) {
+			if len(children) < notionMaxChildren {
+				children = append(children, notionblock.Paragraph(notionblock.Plain(chunk)))
+			}
+		}
+	}
+	return notionblock.Toggle(text...).WithChildren(children...)
+}
+
+// Helper function to build the "High impact" section, empty when nothing is worth enough
+func highImpactBlocks(items []PlannerItem) []notionblock.Block {
+	if len(items) == 0 {
+		return []notionblock.Block{}
+	}
+	blocks := []notionblock.Block{notionblock.Heading2(notionblock.Plain("High impact"))}
+	for _, item := range items {
+		text := []notionblock.RichText{
+			notionblock.Plain(fmt.Sprintf("%.1f%%", item.GradeShare*100)).Bold(),
+			notionblock.Plain(" of " + item.Course + ": " + item.KindLabel() + ": "),
+			notionblock.Plain(item.Title).Link(item.URL),
+		}
+		if !item.Due.IsZero() {
+			text = append(text, notionblock.Plain(" Due at: "+formatTime(item.Due.Format(time.RFC3339))))
+		}
+		if tag := sourceTag(item.Source); tag != "" {
+			text = append(text, notionblock.Plain(tag))
+		}
+		blocks = append(blocks, notionblock.NumberedItem(text...))
+	}
+	return blocks
+}
+
+// Helper function to print my current grade after the course name
+func gradeSuffix(grade CourseGrade) string {
+	if text := grade.Text(); text != "" {
+		return " - currently " + text
+	}
+	return ""
+}
+
+// Helper function to print how many of a course's items have no due date
+func undatedCount(n int) string {
+	switch n {
+	case 0:
+		return ""
+	case 1:
+		return " (1 without a due date)"
+	}
+	return fmt.Sprintf(" (%d without a due date)", n)
 }
 
 // Helper function to split a string into chunks of a specified maximum length
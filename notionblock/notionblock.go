@@ -0,0 +1,420 @@
+// Package notionblock builds the blocks and rich text sent to the Notion API.
+//
+// Blocks are plain values, built with one function per block type:
+//
+//	notionblock.Heading2(notionblock.Plain("E1Lab"))
+//	notionblock.ToDo(false, notionblock.Plain("Lab 3").Link(url), notionblock.Plain(" Due at: ..."))
+//	notionblock.Toggle(notionblock.Plain("Exam moved").Bold()).WithChildren(notionblock.Paragraph(notionblock.Plain(text)))
+package notionblock
+
+// Color is a text or block color. The *Background colors color the background
+// instead of the text.
+type Color string
+
+const (
+	Default          Color = "default"
+	Gray             Color = "gray"
+	Brown            Color = "brown"
+	Orange           Color = "orange"
+	Yellow           Color = "yellow"
+	Green            Color = "green"
+	Blue             Color = "blue"
+	Purple           Color = "purple"
+	Pink             Color = "pink"
+	Red              Color = "red"
+	GrayBackground   Color = "gray_background"
+	BrownBackground  Color = "brown_background"
+	OrangeBackground Color = "orange_background"
+	YellowBackground Color = "yellow_background"
+	GreenBackground  Color = "green_background"
+	BlueBackground   Color = "blue_background"
+	PurpleBackground Color = "purple_background"
+	PinkBackground   Color = "pink_background"
+	RedBackground    Color = "red_background"
+)
+
+// Notion caps a single rich text object at 2000 characters
+const MaxTextLength = 2000
+
+type Annotations struct {
+	Bold          bool  `json:"bold"`
+	Italic        bool  `json:"italic"`
+	Strikethrough bool  `json:"strikethrough"`
+	Underline     bool  `json:"underline"`
+	Code          bool  `json:"code"`
+	Color         Color `json:"color,omitempty"`
+}
+
+type Link struct {
+	URL string `json:"url"`
+}
+
+type Text struct {
+	Content string `json:"content"`
+	Link    *Link  `json:"link,omitempty"`
+}
+
+// RichText is one run of text with the same formatting
+type RichText struct {
+	Type        string      `json:"type"`
+	Text        Text        `json:"text"`
+	Annotations Annotations `json:"annotations"`
+	PlainText   string      `json:"plain_text,omitempty"` // only set on responses
+	Href        string      `json:"href,omitempty"`       // only set on responses
+}
+
+// Plain returns unformatted text
+func Plain(content string) RichText {
+	return RichText{Type: "text", Text: Text{Content: content}}
+}
+
+func (r RichText) Bold() RichText {
+	r.Annotations.Bold = true
+	return r
+}
+
+func (r RichText) Italic() RichText {
+	r.Annotations.Italic = true
+	return r
+}
+
+func (r RichText) Strikethrough() RichText {
+	r.Annotations.Strikethrough = true
+	return r
+}
+
+func (r RichText) Underline() RichText {
+	r.Annotations.Underline = true
+	return r
+}
+
+// Code formats the text as inline code
+func (r RichText) Code() RichText {
+	r.Annotations.Code = true
+	return r
+}
+
+func (r RichText) Color(color Color) RichText {
+	r.Annotations.Color = color
+	return r
+}
+
+// Link makes the text a link, unless url is empty
+func (r RichText) Link(url string) RichText {
+	if url != "" {
+		r.Text.Link = &Link{URL: url}
+	}
+	return r
+}
+
+// Content is the text of some rich text without its formatting
+func Content(text []RichText) string {
+	content := ""
+	for _, r := range text {
+		content += r.Text.Content
+	}
+	return content
+}
+
+// TextContent is the content of paragraphs, list items, toggles and quotes
+type TextContent struct {
+	RichText []RichText `json:"rich_text"`
+	Color    Color      `json:"color,omitempty"`
+	Children []Block    `json:"children,omitempty"`
+}
+
+type HeadingContent struct {
+	RichText     []RichText `json:"rich_text"`
+	Color        Color      `json:"color,omitempty"`
+	IsToggleable bool       `json:"is_toggleable,omitempty"`
+	Children     []Block    `json:"children,omitempty"` // only on toggleable headings
+}
+
+type ToDoContent struct {
+	RichText []RichText `json:"rich_text"`
+	Checked  bool       `json:"checked"`
+	Color    Color      `json:"color,omitempty"`
+	Children []Block    `json:"children,omitempty"`
+}
+
+type Icon struct {
+	Type  string `json:"type"`
+	Emoji string `json:"emoji"`
+}
+
+type CalloutContent struct {
+	RichText []RichText `json:"rich_text"`
+	Icon     *Icon      `json:"icon,omitempty"`
+	Color    Color      `json:"color,omitempty"`
+	Children []Block    `json:"children,omitempty"`
+}
+
+type CodeContent struct {
+	RichText []RichText `json:"rich_text"`
+	Language string     `json:"language"`
+	Caption  []RichText `json:"caption,omitempty"`
+}
+
+type TableContent struct {
+	TableWidth      int     `json:"table_width"`
+	HasColumnHeader bool    `json:"has_column_header"`
+	HasRowHeader    bool    `json:"has_row_header"`
+	Children        []Block `json:"children"` // table_row blocks
+}
+
+type TableRowContent struct {
+	Cells [][]RichText `json:"cells"`
+}
+
+// Block is a Notion block. Exactly one of the content fields is set, the one named by Type.
+type Block struct {
+	Object           string           `json:"object"`
+	Id               string           `json:"id,omitempty"` // only set on responses
+	Type             string           `json:"type"`
+	Paragraph        *TextContent     `json:"paragraph,omitempty"`
+	Heading1         *HeadingContent  `json:"heading_1,omitempty"`
+	Heading2         *HeadingContent  `json:"heading_2,omitempty"`
+	Heading3         *HeadingContent  `json:"heading_3,omitempty"`
+	BulletedListItem *TextContent     `json:"bulleted_list_item,omitempty"`
+	NumberedListItem *TextContent     `json:"numbered_list_item,omitempty"`
+	ToDo             *ToDoContent     `json:"to_do,omitempty"`
+	Toggle           *TextContent     `json:"toggle,omitempty"`
+	Callout          *CalloutContent  `json:"callout,omitempty"`
+	Quote            *TextContent     `json:"quote,omitempty"`
+	Code             *CodeContent     `json:"code,omitempty"`
+	Divider          *struct{}        `json:"divider,omitempty"`
+	Table            *TableContent    `json:"table,omitempty"`
+	TableRow         *TableRowContent `json:"table_row,omitempty"`
+}
+
+func Paragraph(text ...RichText) Block {
+	return Block{Object: "block", Type: "paragraph", Paragraph: &TextContent{RichText: text}}
+}
+
+func Heading1(text ...RichText) Block {
+	return Block{Object: "block", Type: "heading_1", Heading1: &HeadingContent{RichText: text}}
+}
+
+func Heading2(text ...RichText) Block {
+	return Block{Object: "block", Type: "heading_2", Heading2: &HeadingContent{RichText: text}}
+}
+
+func Heading3(text ...RichText) Block {
+	return Block{Object: "block", Type: "heading_3", Heading3: &HeadingContent{RichText: text}}
+}
+
+func BulletedItem(text ...RichText) Block {
+	return Block{Object: "block", Type: "bulleted_list_item", BulletedListItem: &TextContent{RichText: text}}
+}
+
+func NumberedItem(text ...RichText) Block {
+	return Block{Object: "block", Type: "numbered_list_item", NumberedListItem: &TextContent{RichText: text}}
+}
+
+func ToDo(checked bool, text ...RichText) Block {
+	return Block{Object: "block", Type: "to_do", ToDo: &ToDoContent{RichText: text, Checked: checked}}
+}
+
+// Toggle is a block that shows its children when opened, see WithChildren
+func Toggle(text ...RichText) Block {
+	return Block{Object: "block", Type: "toggle", Toggle: &TextContent{RichText: text}}
+}
+
+// Callout is highlighted text behind an emoji, ex. "⚠️"
+func Callout(emoji string, color Color, text ...RichText) Block {
+	callout := &CalloutContent{RichText: text, Color: color}
+	if emoji != "" {
+		callout.Icon = &Icon{Type: "emoji", Emoji: emoji}
+	}
+	return Block{Object: "block", Type: "callout", Callout: callout}
+}
+
+func Quote(text ...RichText) Block {
+	return Block{Object: "block", Type: "quote", Quote: &TextContent{RichText: text}}
+}
+
+// Code is a code block, language is one of Notion's, ex. "go", "json" or "plain text"
+func Code(language, content string) Block {
+	return Block{Object: "block", Type: "code", Code: &CodeContent{RichText: []RichText{Plain(content)}, Language: language}}
+}
+
+func Divider() Block {
+	return Block{Object: "block", Type: "divider", Divider: &struct{}{}}
+}
+
+// Table builds a table with one cell per rich text in each row. With columnHeader
+// the first row is shown as the header.
+func Table(columnHeader bool, rows ...[]RichText) Block {
+	table := &TableContent{HasColumnHeader: columnHeader, Children: []Block{}}
+	for _, row := range rows {
+		if len(row) > table.TableWidth {
+			table.TableWidth = len(row)
+		}
+	}
+	for _, row := range rows {
+		cells := make([][]RichText, table.TableWidth)
+		for i := range cells {
+			cells[i] = []RichText{}
+			if i < len(row) {
+				cells[i] = []RichText{row[i]}
+			}
+		}
+		table.Children = append(table.Children, Block{Object: "block", Type: "table_row", TableRow: &TableRowContent{Cells: cells}})
+	}
+	return Block{Object: "block", Type: "table", Table: table}
+}
+
+// WithColor colors a text block, ex. a GrayBackground paragraph
+func (b Block) WithColor(color Color) Block {
+	switch {
+	case b.Paragraph != nil:
+		b.Paragraph = withColor(*b.Paragraph, color)
+	case b.BulletedListItem != nil:
+		b.BulletedListItem = withColor(*b.BulletedListItem, color)
+	case b.NumberedListItem != nil:
+		b.NumberedListItem = withColor(*b.NumberedListItem, color)
+	case b.Toggle != nil:
+		b.Toggle = withColor(*b.Toggle, color)
+	case b.Quote != nil:
+		b.Quote = withColor(*b.Quote, color)
+	case b.Heading1 != nil, b.Heading2 != nil, b.Heading3 != nil:
+		heading := *b.heading()
+		heading.Color = color
+		b.setHeading(&heading)
+	case b.ToDo != nil:
+		todo := *b.ToDo
+		todo.Color = color
+		b.ToDo = &todo
+	case b.Callout != nil:
+		callout := *b.Callout
+		callout.Color = color
+		b.Callout = &callout
+	}
+	return b
+}
+
+// WithChildren nests blocks under a block that can have children. Headings
+// become toggleable headings. Other blocks are returned unchanged.
+func (b Block) WithChildren(children ...Block) Block {
+	switch {
+	case b.Paragraph != nil:
+		b.Paragraph = withChildren(*b.Paragraph, children)
+	case b.BulletedListItem != nil:
+		b.BulletedListItem = withChildren(*b.BulletedListItem, children)
+	case b.NumberedListItem != nil:
+		b.NumberedListItem = withChildren(*b.NumberedListItem, children)
+	case b.Toggle != nil:
+		b.Toggle = withChildren(*b.Toggle, children)
+	case b.Quote != nil:
+		b.Quote = withChildren(*b.Quote, children)
+	case b.Heading1 != nil, b.Heading2 != nil, b.Heading3 != nil:
+		heading := *b.heading()
+		heading.IsToggleable = true
+		heading.Children = children
+		b.setHeading(&heading)
+	case b.ToDo != nil:
+		todo := *b.ToDo
+		todo.Children = children
+		b.ToDo = &todo
+	case b.Callout != nil:
+		callout := *b.Callout
+		callout.Children = children
+		b.Callout = &callout
+	}
+	return b
+}
+
+// Text is the rich text of a block, nil for blocks without any (dividers, tables)
+func (b Block) Text() []RichText {
+	switch {
+	case b.Paragraph != nil:
+		return b.Paragraph.RichText
+	case b.BulletedListItem != nil:
+		return b.BulletedListItem.RichText
+	case b.NumberedListItem != nil:
+		return b.NumberedListItem.RichText
+	case b.Toggle != nil:
+		return b.Toggle.RichText
+	case b.Quote != nil:
+		return b.Quote.RichText
+	case b.Heading1 != nil, b.Heading2 != nil, b.Heading3 != nil:
+		return b.heading().RichText
+	case b.ToDo != nil:
+		return b.ToDo.RichText
+	case b.Callout != nil:
+		return b.Callout.RichText
+	case b.Code != nil:
+		return b.Code.RichText
+	}
+	return nil
+}
+
+// Children are the blocks nested under a block
+func (b Block) Children() []Block {
+	switch {
+	case b.Paragraph != nil:
+		return b.Paragraph.Children
+	case b.BulletedListItem != nil:
+		return b.BulletedListItem.Children
+	case b.NumberedListItem != nil:
+		return b.NumberedListItem.Children
+	case b.Toggle != nil:
+		return b.Toggle.Children
+	case b.Quote != nil:
+		return b.Quote.Children
+	case b.Heading1 != nil, b.Heading2 != nil, b.Heading3 != nil:
+		return b.heading().Children
+	case b.ToDo != nil:
+		return b.ToDo.Children
+	case b.Callout != nil:
+		return b.Callout.Children
+	case b.Table != nil:
+		return b.Table.Children
+	}
+	return nil
+}
+
+// Size counts the block and every block nested under it, which is what
+// Notion's per-request block limit counts
+func (b Block) Size() int {
+	size := 1
+	for _, child := range b.Children() {
+		size += child.Size()
+	}
+	return size
+}
+
+// Helper function to copy text content with another color, so blocks stay values
+func withColor(content TextContent, color Color) *TextContent {
+	content.Color = color
+	return &content
+}
+
+// Helper function to copy text content with children
+func withChildren(content TextContent, children []Block) *TextContent {
+	content.Children = children
+	return &content
+}
+
+// Helper function to get whichever heading level is set
+func (b Block) heading() *HeadingContent {
+	switch {
+	case b.Heading1 != nil:
+		return b.Heading1
+	case b.Heading2 != nil:
+		return b.Heading2
+	}
+	return b.Heading3
+}
+
+// Helper function to replace the content of whichever heading level is set
+func (b *Block) setHeading(heading *HeadingContent) {
+	switch {
+	case b.Heading1 != nil:
+		b.Heading1 = heading
+	case b.Heading2 != nil:
+		b.Heading2 = heading
+	default:
+		b.Heading3 = heading
+	}
+}
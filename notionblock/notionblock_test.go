@@ -0,0 +1,103 @@
+package notionblock
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+// Helper function to check that v marshals to the same JSON as want, whatever the spacing
+func assertJSON(t *testing.T, v any, want string) {
+	t.Helper()
+	got, err := json.Marshal(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var gotValue, wantValue any
+	if err := json.Unmarshal(got, &gotValue); err != nil {
+		t.Fatal(err)
+	}
+	if err := json.Unmarshal([]byte(want), &wantValue); err != nil {
+		t.Fatalf("bad want JSON: %v", err)
+	}
+	if !reflect.DeepEqual(gotValue, wantValue) {
+		t.Errorf("got  %s\nwant %s", got, want)
+	}
+}
+
+func TestDivider(t *testing.T) {
+	assertJSON(t, Divider(), `{"object": "block", "type": "divider", "divider": {}}`)
+}
+
+func TestRichTextAnnotationsAndLink(t *testing.T) {
+	text := Plain("PA#1").Bold().Italic().Strikethrough().Underline().Code().Color(Red).Link("https://webcourses.ucf.edu/courses/1464092/assignments/8608633")
+	assertJSON(t, text, `{
+		"type": "text",
+		"text": {"content": "PA#1", "link": {"url": "https://webcourses.ucf.edu/courses/1464092/assignments/8608633"}},
+		"annotations": {"bold": true, "italic": true, "strikethrough": true, "underline": true, "code": true, "color": "red"}
+	}`)
+
+	// No link object at all for an empty url, Notion rejects {"url": ""}
+	assertJSON(t, Plain("plain").Link(""), `{
+		"type": "text",
+		"text": {"content": "plain"},
+		"annotations": {"bold": false, "italic": false, "strikethrough": false, "underline": false, "code": false}
+	}`)
+}
+
+func TestToggleableHeading(t *testing.T) {
+	heading := Heading3(Plain("Geology"))
+	toggle := heading.WithChildren(ToDo(true, Plain("Lab 3")))
+	assertJSON(t, toggle, `{
+		"object": "block",
+		"type": "heading_3",
+		"heading_3": {
+			"rich_text": [{"type": "text", "text": {"content": "Geology"}, "annotations": {"bold": false, "italic": false, "strikethrough": false, "underline": false, "code": false}}],
+			"is_toggleable": true,
+			"children": [{
+				"object": "block",
+				"type": "to_do",
+				"to_do": {
+					"rich_text": [{"type": "text", "text": {"content": "Lab 3"}, "annotations": {"bold": false, "italic": false, "strikethrough": false, "underline": false, "code": false}}],
+					"checked": true
+				}
+			}]
+		}
+	}`)
+
+	// Blocks are values, the heading it was built from stays a plain heading
+	assertJSON(t, heading.Heading3, `{"rich_text": [{"type": "text", "text": {"content": "Geology"}, "annotations": {"bold": false, "italic": false, "strikethrough": false, "underline": false, "code": false}}]}`)
+	if toggle.Size() != 2 {
+		t.Errorf("Size() = %d, want 2", toggle.Size())
+	}
+}
+
+func TestTable(t *testing.T) {
+	table := Table(true,
+		[]RichText{Plain("Course"), Plain("Grade")},
+		[]RichText{Plain("OS")}, // short rows are padded with empty cells
+	)
+	empty := `{"bold": false, "italic": false, "strikethrough": false, "underline": false, "code": false}`
+	assertJSON(t, table, `{
+		"object": "block",
+		"type": "table",
+		"table": {
+			"table_width": 2,
+			"has_column_header": true,
+			"has_row_header": false,
+			"children": [
+				{"object": "block", "type": "table_row", "table_row": {"cells": [
+					[{"type": "text", "text": {"content": "Course"}, "annotations": `+empty+`}],
+					[{"type": "text", "text": {"content": "Grade"}, "annotations": `+empty+`}]
+				]}},
+				{"object": "block", "type": "table_row", "table_row": {"cells": [
+					[{"type": "text", "text": {"content": "OS"}, "annotations": `+empty+`}],
+					[]
+				]}}
+			]
+		}
+	}`)
+	if table.Size() != 3 {
+		t.Errorf("Size() = %d, want 3", table.Size())
+	}
+}
@@ -0,0 +1,388 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/landerson7/chatgptnotionplanner/notionblock"
+)
+
+// Database mode: instead of a new page every day, every item is a row of the
+// NOTION_DATABASE_ID database, found again on the next run by its Canvas ID.
+// The run only writes the properties that come from Canvas, so Status (past
+// its first value) and Notes are left to whoever uses the database.
+
+// Property names of the assignments database
+const (
+	PropName     = "Name"
+	PropCourse   = "Course"
+	PropDue      = "Due"
+	PropType     = "Type"
+	PropPoints   = "Points"
+	PropURL      = "Canvas URL"
+	PropStatus   = "Status"
+	PropNotes    = "Notes"
+	PropCanvasID = "Canvas ID" // ItemKey of the item, hide it in the database views
+)
+
+// Status options. New rows start as StatusToDo, or StatusDone when already submitted.
+const (
+	StatusToDo       = "To do"
+	StatusInProgress = "In progress"
+	StatusDone       = "Done"
+)
+
+// Helper function to read the database rows are synced to, empty for the daily page mode
+func NotionDatabaseID() string {
+	return GetEnvVar("NOTION_DATABASE_ID", "", "", "database")
+}
+
+// UseNotionDatabase reports whether items are synced to a database instead of a daily page
+func UseNotionDatabase() bool {
+	return NotionDatabaseID() != ""
+}
+
+type notionSelect struct {
+	Name  string `json:"name"`
+	Color string `json:"color,omitempty"`
+}
+
+type notionDate struct {
+	Start string  `json:"start"`
+	End   *string `json:"end"`
+}
+
+// A property value as Notion sends it back
+type notionPropertyValue struct {
+	Type     string                 `json:"type"`
+	Title    []notionblock.RichText `json:"title"`
+	RichText []notionblock.RichText `json:"rich_text"`
+	Select   *notionSelect          `json:"select"`
+	Date     *notionDate            `json:"date"`
+	Number   *float64               `json:"number"`
+	URL      *string                `json:"url"`
+}
+
+type notionDatabasePage struct {
+	ID         string                         `json:"id"`
+	Properties map[string]notionPropertyValue `json:"properties"`
+}
+
+type notionQueryResponse struct {
+	Results    []notionDatabasePage `json:"results"`
+	HasMore    bool                 `json:"has_more"`
+	NextCursor string               `json:"next_cursor"`
+}
+
+// NotionRow is the part of a database row the sync reads and writes
+type NotionRow struct {
+	PageID   string
+	CanvasID string
+	Title    string
+	Course   string
+	Type     string
+	Due      string // RFC3339, empty without a date
+	DueEnd   string // only for events
+	Points   *float64
+	URL      string
+	Status   string
+}
+
+// Helper function to build the row an item should have
+func rowForItem(item PlannerItem) NotionRow {
+	row := NotionRow{
+		CanvasID: ItemKey(item),
+		Title:    item.Title,
+		Course:   selectName(item.Course),
+		Type:     item.KindLabel(),
+		URL:      item.URL,
+		Status:   StatusToDo,
+	}
+	if !item.Due.IsZero() {
+		row.Due = item.Due.Format(time.RFC3339)
+	}
+	if !item.End.IsZero() {
+		row.DueEnd = item.End.Format(time.RFC3339)
+	}
+	if item.Points > 0 {
+		points := item.Points
+		row.Points = &points
+	}
+	if item.Completed {
+		row.Status = StatusDone
+	}
+	return row
+}
+
+// Helper function to read a row back from a database page
+func rowFromPage(page notionDatabasePage) NotionRow {
+	row := NotionRow{PageID: page.ID}
+	props := page.Properties
+	row.CanvasID = notionblock.Content(props[PropCanvasID].RichText)
+	row.Title = notionblock.Content(props[PropName].Title)
+	if s := props[PropCourse].Select; s != nil {
+		row.Course = s.Name
+	}
+	if s := props[PropType].Select; s != nil {
+		row.Type = s.Name
+	}
+	if s := props[PropStatus].Select; s != nil {
+		row.Status = s.Name
+	}
+	if d := props[PropDue].Date; d != nil {
+		row.Due = d.Start
+		if d.End != nil {
+			row.DueEnd = *d.End
+		}
+	}
+	row.Points = props[PropPoints].Number
+	if u := props[PropURL].URL; u != nil {
+		row.URL = *u
+	}
+	return row
+}
+
+// Helper function to make a name usable as a select option, which can't contain commas
+func selectName(name string) string {
+	return strings.Join(strings.Fields(strings.ReplaceAll(name, ",", " ")), " ")
+}
+
+// Helper function to build the Canvas-owned properties of a row. Status is only
+// included when it should be written.
+func (row NotionRow) properties(withStatus bool) map[string]interface{} {
+	var due interface{}
+	if row.Due != "" {
+		date := map[string]interface{}{"start": row.Due}
+		if row.DueEnd != "" {
+			date["end"] = row.DueEnd
+		}
+		due = date
+	}
+	var url interface{}
+	if row.URL != "" {
+		url = row.URL
+	}
+	var points interface{}
+	if row.Points != nil {
+		points = *row.Points
+	}
+
+	props := map[string]interface{}{
+		PropName:     map[string]interface{}{"title": []notionblock.RichText{notionblock.Plain(row.Title)}},
+		PropCanvasID: map[string]interface{}{"rich_text": []notionblock.RichText{notionblock.Plain(row.CanvasID)}},
+		PropCourse:   map[string]interface{}{"select": selectValue(row.Course)},
+		PropType:     map[string]interface{}{"select": selectValue(row.Type)},
+		PropDue:      map[string]interface{}{"date": due},
+		PropPoints:   map[string]interface{}{"number": points},
+		PropURL:      map[string]interface{}{"url": url},
+	}
+	if withStatus {
+		props[PropStatus] = map[string]interface{}{"select": selectValue(row.Status)}
+	}
+	return props
+}
+
+// Helper function to build a select value, empty names clear the select
+func selectValue(name string) interface{} {
+	if name == "" {
+		return nil
+	}
+	return map[string]string{"name": name}
+}
+
+// Helper function to check if the Canvas-owned properties of two rows match.
+// Dates are compared as times, Notion sends them back in its own format.
+func (row NotionRow) sameCanvasFields(other NotionRow) bool {
+	samePoints := (row.Points == nil) == (other.Points == nil) && (row.Points == nil || *row.Points == *other.Points)
+	return row.Title == other.Title && row.Course == other.Course && row.Type == other.Type &&
+		row.URL == other.URL && samePoints && sameDate(row.Due, other.Due) && sameDate(row.DueEnd, other.DueEnd)
+}
+
+// Helper function to compare two RFC3339 dates that may be written differently
+func sameDate(a, b string) bool {
+	if a == "" || b == "" {
+		return a == b
+	}
+	ta, errA := time.Parse(time.RFC3339, a)
+	tb, errB := time.Parse(time.RFC3339, b)
+	if errA != nil || errB != nil {
+		return a == b
+	}
+	return ta.Equal(tb)
+}
+
+// QueryNotionRows reads every synced row of the database, keyed by Canvas ID
+func QueryNotionRows(databaseID string) (map[string]NotionRow, error) {
+	url := "https://api.notion.com/v1/databases/" + databaseID + "/query"
+	rows := make(map[string]NotionRow)
+
+	cursor := ""
+	for {
+		query := map[string]interface{}{
+			"filter":    map[string]interface{}{"property": PropCanvasID, "rich_text": map[string]bool{"is_not_empty": true}},
+			"page_size": 100,
+		}
+		if cursor != "" {
+			query["start_cursor"] = cursor
+		}
+		sendData, err := json.Marshal(query)
+		if err != nil {
+			return nil, err
+		}
+		// A replay has no database to read, so every item comes out as a new row
+		if writeReplayRequest("notion_query_database", "POST", url, sendData) {
+			return rows, nil
+		}
+
+		body, err := notionDo("POST", url, sendData)
+		if err != nil {
+			return nil, err
+		}
+		var response notionQueryResponse
+		if err := json.Unmarshal(body, &response); err != nil {
+			return nil, err
+		}
+		for _, page := range response.Results {
+			row := rowFromPage(page)
+			if _, ok := rows[row.CanvasID]; ok {
+				fmt.Println("Duplicate Notion row for", row.CanvasID+", keeping the first one")
+				continue
+			}
+			rows[row.CanvasID] = row
+		}
+
+		if !response.HasMore || response.NextCursor == "" {
+			return rows, nil
+		}
+		cursor = response.NextCursor
+	}
+}
+
+// Helper function to add a row to the database
+func createNotionRow(databaseID string, row NotionRow) error {
+	url := "https://api.notion.com/v1/pages"
+
+	sendData, err := json.Marshal(map[string]interface{}{
+		"parent":     map[string]string{"database_id": databaseID},
+		"properties": row.properties(true),
+	})
+	if err != nil {
+		return err
+	}
+	if writeReplayRequest("notion_create_row", "POST", url, sendData) {
+		return nil
+	}
+	_, err = notionDo("POST", url, sendData)
+	return err
+}
+
+// Helper function to rewrite the Canvas-owned properties of an existing row
+func updateNotionRow(row NotionRow, withStatus bool) error {
+	url := "https://api.notion.com/v1/pages/" + row.PageID
+
+	sendData, err := json.Marshal(map[string]interface{}{"properties": row.properties(withStatus)})
+	if err != nil {
+		return err
+	}
+	if writeReplayRequest("notion_update_row", "PATCH", url, sendData) {
+		return nil
+	}
+	_, err = notionDo("PATCH", url, sendData)
+	return err
+}
+
+// UpsertNotionRows creates a row for every item the database doesn't have yet and
+// updates the rows whose Canvas details changed. Rows are never deleted, and the
+// only Status change made is marking a still "To do" row done once it's submitted.
+// It returns the rows as they were before the run, keyed by Canvas ID.
+func UpsertNotionRows(databaseID string, items []PlannerItem) (map[string]NotionRow, error) {
+	existing, err := QueryNotionRows(databaseID)
+	if err != nil {
+		// Without the existing rows every item would be created again
+		return nil, fmt.Errorf("error reading Notion database: %w", err)
+	}
+
+	created, updated, failed := 0, 0, 0
+	seen := make(map[string]bool)
+	for _, item := range items {
+		row := rowForItem(item)
+		if seen[row.CanvasID] {
+			continue
+		}
+		seen[row.CanvasID] = true
+
+		old, ok := existing[row.CanvasID]
+		if !ok {
+			if err := createNotionRow(databaseID, row); err != nil {
+				fmt.Println("Error creating Notion row for "+row.Title+":", err)
+				failed++
+				continue
+			}
+			created++
+			continue
+		}
+
+		markDone := row.Status == StatusDone && (old.Status == "" || old.Status == StatusToDo)
+		if row.sameCanvasFields(old) && !markDone {
+			continue
+		}
+		row.PageID = old.PageID
+		if err := updateNotionRow(row, markDone); err != nil {
+			fmt.Println("Error updating Notion row for "+row.Title+":", err)
+			failed++
+			continue
+		}
+		updated++
+	}
+
+	fmt.Printf("Notion database: %d created, %d updated, %d unchanged, %d failed\n", created, updated, len(seen)-created-updated-failed, failed)
+	if failed > 0 {
+		return existing, fmt.Errorf("%d of %d Notion rows could not be written", failed, len(seen))
+	}
+	return existing, nil
+}
+
+// SyncAllAssignmentsToNotionDatabase is the database mode counterpart of
+// SendAllAssignmentsToOneNotionPage. Items due within a month and undated items
+// are upserted, and the items are returned for the other outputs.
+func SyncAllAssignmentsToNotionDatabase(ctx context.Context, now time.Time) []CourseItems {
+	courses := LoadCourses(ctx, now)
+	groups := CollectCourseItems(ctx, courses, now, now.AddDate(0, 1, 0))
+
+	items := []PlannerItem{}
+	for _, group := range groups {
+		for _, fetchErr := range group.Errors {
+			fmt.Println("Could not fetch "+fetchErr.What+" for "+group.Course.Name+", its rows may be out of date:", fetchErr.Err)
+		}
+		items = append(items, group.Items...)
+		items = append(items, group.Undated...)
+	}
+
+	rows, err := UpsertNotionRows(NotionDatabaseID(), items)
+	if err != nil {
+		fmt.Println(err)
+	}
+
+	// Rows I set to Done stay completed in the history, like checked to-dos in page mode
+	historyPath := GetHistoryPath()
+	history, historyErr := LoadHistory(historyPath)
+	if historyErr != nil {
+		// Saving would replace every recorded completion with just this run's Done rows
+		fmt.Println("Error loading history, it won't be saved over this run:", historyErr)
+	}
+	for _, item := range items {
+		if row, ok := rows[ItemKey(item)]; ok {
+			history.SetCompleted(ItemKey(item), row.Status == StatusDone, now)
+		}
+	}
+	history.ApplyCompletions(groups, now)
+	if !ReplayMode() && historyErr == nil {
+		if err := history.Save(historyPath); err != nil {
+			fmt.Println("Error saving history:", err)
+		}
+	}
+	return groups
+}
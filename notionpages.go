@@ -0,0 +1,269 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/landerson7/chatgptnotionplanner/notionblock"
+)
+
+// Notion counts every block in a request, nested ones included, up to 1000
+const notionMaxBlocksPerRequest = 1000
+
+// Page id used in the append requests written during a replay, where no page is created
+const replayPageID = "replay-page-id"
+
+// Notion allows about 3 requests a second and answers 429 above that, with a
+// Retry-After header saying how many seconds to wait
+const (
+	notionMaxRetries     = 5
+	notionRetryBaseDelay = time.Second
+)
+
+// NotionPageError is returned when a page was created but not all of its blocks
+// made it onto it. The page exists, it is just missing everything after Appended.
+type NotionPageError struct {
+	PageID   string
+	Appended int // top-level blocks on the page
+	Total    int
+	Err      error
+}
+
+func (e *NotionPageError) Error() string {
+	return fmt.Sprintf("notion page %s only got %d of %d blocks: %v", e.PageID, e.Appended, e.Total, e.Err)
+}
+
+func (e *NotionPageError) Unwrap() error {
+	return e.Err
+}
+
+// Helper function to send a request to the Notion API and return the response
+// body, with any status other than 200 as an error. Rate-limited requests are
+// retried after the wait Notion asks for, or an exponential back-off without one.
+func notionDo(method, url string, sendData []byte) ([]byte, error) {
+	apiKey := GetEnvVar("NOTION_API")
+	client := &http.Client{}
+	for attempt := 0; ; attempt++ {
+		req, err := http.NewRequest(method, url, bytes.NewBuffer(sendData))
+		if err != nil {
+			return nil, err
+		}
+
+		req.Header.Add("Authorization", "Bearer "+apiKey)
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Notion-Version", "2022-06-28")
+
+		resp, err := client.Do(req)
+		if err != nil {
+			return nil, err
+		}
+		body, err := ioutil.ReadAll(resp.Body)
+		resp.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+
+		if resp.StatusCode == http.StatusTooManyRequests && attempt < notionMaxRetries {
+			delay := notionRetryBaseDelay << attempt
+			if seconds, err := strconv.Atoi(resp.Header.Get("Retry-After")); err == nil && seconds >= 0 {
+				delay = time.Duration(seconds) * time.Second
+			}
+			fmt.Printf("Notion rate limit hit, retrying in %s\n", delay)
+			time.Sleep(delay)
+			continue
+		}
+		if resp.StatusCode != http.StatusOK {
+			return body, fmt.Errorf("notion %s %s: %s: %s", method, url, resp.Status, body)
+		}
+		return body, nil
+	}
+}
+
+// Helper function to split blocks into batches Notion accepts in one request:
+// at most 100 top-level blocks and 1000 blocks counting nested ones
+func batchBlocks(blocks []notionblock.Block) [][]notionblock.Block {
+	batches := [][]notionblock.Block{}
+	batch := []notionblock.Block{}
+	size := 0
+	for _, block := range blocks {
+		if len(batch) == notionMaxChildren || (len(batch) > 0 && size+block.Size() > notionMaxBlocksPerRequest) {
+			batches = append(batches, batch)
+			batch, size = []notionblock.Block{}, 0
+		}
+		batch = append(batch, block)
+		size += block.Size()
+	}
+	if len(batch) > 0 {
+		batches = append(batches, batch)
+	}
+	return batches
+}
+
+// CreateNotionPage creates the page with as many of its children as fit in one
+// request and appends the rest in batches. It returns the new page's id, which
+// is empty during a replay. When appending fails part way the page is left as
+// it is and a *NotionPageError says how far it got.
+func CreateNotionPage(notionRequest NotionRequest) (string, error) {
+	url := "https://api.notion.com/v1/pages"
+
+	batches := batchBlocks(notionRequest.Children)
+	notionRequest.Children = []notionblock.Block{}
+	if len(batches) > 0 {
+		notionRequest.Children, batches = batches[0], batches[1:]
+	}
+
+	sendData, err := json.Marshal(notionRequest)
+	if err != nil {
+		return "", err
+	}
+
+	pageID := replayPageID
+	if !writeReplayRequest("notion_create_page", "POST", url, sendData) {
+		body, err := notionDo("POST", url, sendData)
+		if err != nil {
+			return "", err
+		}
+		fmt.Println("Raw response body:", string(body))
+
+		var page struct {
+			ID string `json:"id"`
+		}
+		if err := json.Unmarshal(body, &page); err != nil {
+			return "", err
+		}
+		pageID = page.ID
+	}
+
+	appended := len(notionRequest.Children)
+	total := appended
+	for _, batch := range batches {
+		total += len(batch)
+	}
+	for _, batch := range batches {
+		if err := AppendNotionBlocks(pageID, batch); err != nil {
+			return pageID, &NotionPageError{PageID: pageID, Appended: appended, Total: total, Err: err}
+		}
+		appended += len(batch)
+	}
+
+	if pageID == replayPageID {
+		return "", nil
+	}
+	return pageID, nil
+}
+
+// AppendNotionBlocks adds blocks to the end of a page or block. The blocks must
+// fit in one request, see batchBlocks.
+func AppendNotionBlocks(blockID string, blocks []notionblock.Block) error {
+	url := "https://api.notion.com/v1/blocks/" + blockID + "/children"
+
+	sendData, err := json.Marshal(struct {
+		Children []notionblock.Block `json:"children"`
+	}{blocks})
+	if err != nil {
+		return err
+	}
+	if writeReplayRequest("notion_append_blocks", "PATCH", url, sendData) {
+		return nil
+	}
+
+	_, err = notionDo("PATCH", url, sendData)
+	return err
+}
+
+// Helper function to flag a partly written page at its end, so it doesn't pass
+// for a complete one. Errors other than a *NotionPageError left no page behind.
+func reportIncompletePage(err error) {
+	var partial *NotionPageError
+	if !errors.As(err, &partial) {
+		return
+	}
+	warning := notionblock.Callout("⚠️", notionblock.RedBackground,
+		notionblock.Plain("This page is incomplete: ").Bold(),
+		notionblock.Plain(fmt.Sprintf("only %d of its %d blocks could be added.", partial.Appended, partial.Total)),
+	)
+	if err := AppendNotionBlocks(partial.PageID, []notionblock.Block{warning}); err != nil {
+		fmt.Println("Error flagging incomplete Notion page:", err)
+	}
+}
+
+// FindNotionPagesByName returns the ids of the pages titled exactly pageName.
+// Notion's search also matches partial titles, so every hit is checked.
+func FindNotionPagesByName(pageName string) ([]string, error) {
+	searchURL := "https://api.notion.com/v1/search"
+
+	searchRequest := NotionSearchRequest{
+		Query: pageName,
+	}
+	searchRequest.Filter.Value = "page"
+	searchRequest.Filter.Property = "object"
+
+	searchData, err := json.Marshal(searchRequest)
+	if err != nil {
+		return nil, err
+	}
+	// A replay has no Notion pages to look up, so recording the search is as far as it goes
+	if writeReplayRequest("notion_search", "POST", searchURL, searchData) {
+		return nil, nil
+	}
+
+	body, err := notionDo("POST", searchURL, searchData)
+	if err != nil {
+		return nil, err
+	}
+	var searchResponse NotionSearchResponse
+	if err := json.Unmarshal(body, &searchResponse); err != nil {
+		return nil, err
+	}
+
+	pageIDs := []string{}
+	for _, result := range searchResponse.Results {
+		pageDetails, err := getPageDetails(result.ID)
+		if err != nil {
+			fmt.Println("Error fetching page details:", err)
+			continue
+		}
+		title := pageDetails.Properties.Title.Title
+		if len(title) > 0 && notionblock.Content(title) == pageName {
+			pageIDs = append(pageIDs, result.ID)
+		}
+	}
+	return pageIDs, nil
+}
+
+// GetNotionBlockChildren returns every block directly under a page or block
+func GetNotionBlockChildren(blockID string) ([]notionblock.Block, error) {
+	blocks := []notionblock.Block{}
+	cursor := ""
+	for {
+		url := "https://api.notion.com/v1/blocks/" + blockID + "/children?page_size=100"
+		if cursor != "" {
+			url += "&start_cursor=" + cursor
+		}
+		body, err := notionDo("GET", url, nil)
+		if err != nil {
+			return nil, err
+		}
+
+		var response struct {
+			Results    []notionblock.Block `json:"results"`
+			HasMore    bool                `json:"has_more"`
+			NextCursor string              `json:"next_cursor"`
+		}
+		if err := json.Unmarshal(body, &response); err != nil {
+			return nil, err
+		}
+		blocks = append(blocks, response.Results...)
+
+		if !response.HasMore || response.NextCursor == "" {
+			return blocks, nil
+		}
+		cursor = response.NextCursor
+	}
+}
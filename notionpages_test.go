@@ -0,0 +1,53 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestNotionDoRetriesRateLimits(t *testing.T) {
+	requests := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		if body, _ := io.ReadAll(r.Body); string(body) != `{"n":1}` {
+			t.Errorf("attempt %d sent %q, want the whole body again", requests, body)
+		}
+		if requests < 3 {
+			w.Header().Set("Retry-After", "0")
+			w.WriteHeader(http.StatusTooManyRequests)
+			fmt.Fprint(w, `{"object": "error", "code": "rate_limited"}`)
+			return
+		}
+		fmt.Fprint(w, `{"id": "page"}`)
+	}))
+	defer srv.Close()
+
+	body, err := notionDo("POST", srv.URL, []byte(`{"n":1}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(body) != `{"id": "page"}` || requests != 3 {
+		t.Errorf("got %s after %d requests, want the page after 3", body, requests)
+	}
+}
+
+func TestNotionDoErrorStatus(t *testing.T) {
+	requests := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		w.WriteHeader(http.StatusBadRequest)
+		fmt.Fprint(w, `{"object": "error", "code": "validation_error"}`)
+	}))
+	defer srv.Close()
+
+	if _, err := notionDo("PATCH", srv.URL, nil); err == nil || !strings.Contains(err.Error(), "validation_error") {
+		t.Errorf("err = %v, want Notion's validation error", err)
+	}
+	if requests != 1 {
+		t.Errorf("made %d requests, want 1", requests)
+	}
+}
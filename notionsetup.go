@@ -0,0 +1,313 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/landerson7/chatgptnotionplanner/notionblock"
+)
+
+// Colors handed out to new select options in turn, "default" is left out so every option stands out
+var notionOptionColors = []notionblock.Color{
+	notionblock.Blue, notionblock.Green, notionblock.Orange, notionblock.Purple, notionblock.Pink,
+	notionblock.Red, notionblock.Yellow, notionblock.Brown, notionblock.Gray,
+}
+
+// A property of a database schema, as sent to and read from Notion
+type notionSchemaProperty struct {
+	ID       string              `json:"id,omitempty"`
+	Name     string              `json:"name,omitempty"`
+	Type     string              `json:"type"`
+	Title    *struct{}           `json:"title,omitempty"`
+	RichText *struct{}           `json:"rich_text,omitempty"`
+	Date     *struct{}           `json:"date,omitempty"`
+	URL      *struct{}           `json:"url,omitempty"`
+	Number   *notionNumberFormat `json:"number,omitempty"`
+	Select   *notionSelectSchema `json:"select,omitempty"`
+}
+
+type notionNumberFormat struct {
+	Format string `json:"format"`
+}
+
+type notionSelectSchema struct {
+	Options []notionSelectOption `json:"options"`
+}
+
+type notionSelectOption struct {
+	ID    string            `json:"id,omitempty"`
+	Name  string            `json:"name"`
+	Color notionblock.Color `json:"color,omitempty"`
+}
+
+type notionDatabase struct {
+	ID         string                          `json:"id"`
+	Title      []notionblock.RichText          `json:"title"`
+	Properties map[string]notionSchemaProperty `json:"properties"`
+}
+
+// Helper function to read the page new databases and daily pages are created under
+func NotionParentPageID() string {
+	return GetEnvVar("NOTION_PARENT_PAGE_ID", notionParentPageID, "", "parent-page")
+}
+
+// Helper function to read the title of the assignments database
+func notionDatabaseTitle() string {
+	return GetEnvVar("NOTION_DATABASE_TITLE", "Canvas Assignments", "", "database-title")
+}
+
+// Helper function to build a select schema with the given options, colored in turn
+func selectSchema(names []string) *notionSelectSchema {
+	schema := &notionSelectSchema{Options: []notionSelectOption{}}
+	for _, name := range names {
+		schema.Options = append(schema.Options, notionSelectOption{Name: name, Color: notionOptionColors[len(schema.Options)%len(notionOptionColors)]})
+	}
+	return schema
+}
+
+// NotionDatabaseSchema is the property schema the database sync needs, with one
+// Course option per course
+func NotionDatabaseSchema(courses []Course) map[string]notionSchemaProperty {
+	courseNames := []string{}
+	seen := make(map[string]bool)
+	for _, course := range courses {
+		name := selectName(course.Name)
+		if name != "" && !seen[name] {
+			seen[name] = true
+			courseNames = append(courseNames, name)
+		}
+	}
+	types := []string{}
+	for _, kind := range []string{ItemAssignment, ItemDiscussion, ItemQuiz, ItemEvent, ItemAnnouncement, ItemNote, ItemPage, ""} {
+		types = append(types, PlannerItem{Kind: kind}.KindLabel())
+	}
+
+	statusSchema := &notionSelectSchema{Options: []notionSelectOption{
+		{Name: StatusToDo, Color: notionblock.Gray},
+		{Name: StatusInProgress, Color: notionblock.Blue},
+		{Name: StatusDone, Color: notionblock.Green},
+	}}
+
+	return map[string]notionSchemaProperty{
+		PropName:     {Type: "title", Title: &struct{}{}},
+		PropCourse:   {Type: "select", Select: selectSchema(courseNames)},
+		PropDue:      {Type: "date", Date: &struct{}{}},
+		PropType:     {Type: "select", Select: selectSchema(types)},
+		PropPoints:   {Type: "number", Number: &notionNumberFormat{Format: "number"}},
+		PropURL:      {Type: "url", URL: &struct{}{}},
+		PropStatus:   {Type: "select", Select: statusSchema},
+		PropNotes:    {Type: "rich_text", RichText: &struct{}{}},
+		PropCanvasID: {Type: "rich_text", RichText: &struct{}{}},
+	}
+}
+
+// Helper function to find a database by its exact title under a parent page
+func findNotionDatabase(parentPageID, title string) (string, error) {
+	url := "https://api.notion.com/v1/search"
+
+	sendData, err := json.Marshal(map[string]interface{}{
+		"query":  title,
+		"filter": map[string]string{"value": "database", "property": "object"},
+	})
+	if err != nil {
+		return "", err
+	}
+	if writeReplayRequest("notion_search", "POST", url, sendData) {
+		return "", nil
+	}
+
+	body, err := notionDo("POST", url, sendData)
+	if err != nil {
+		return "", err
+	}
+	var response struct {
+		Results []struct {
+			ID     string                 `json:"id"`
+			Title  []notionblock.RichText `json:"title"`
+			Parent struct {
+				PageID string `json:"page_id"`
+			} `json:"parent"`
+		} `json:"results"`
+	}
+	if err := json.Unmarshal(body, &response); err != nil {
+		return "", err
+	}
+	for _, result := range response.Results {
+		if notionblock.Content(result.Title) == title && sameNotionID(result.Parent.PageID, parentPageID) {
+			return result.ID, nil
+		}
+	}
+	return "", nil
+}
+
+// Helper function to compare Notion ids, which come with or without dashes
+func sameNotionID(a, b string) bool {
+	return strings.ReplaceAll(a, "-", "") == strings.ReplaceAll(b, "-", "")
+}
+
+// Helper function to read a database and its schema
+func getNotionDatabase(databaseID string) (notionDatabase, error) {
+	var database notionDatabase
+	body, err := notionDo("GET", "https://api.notion.com/v1/databases/"+databaseID, nil)
+	if err != nil {
+		return database, err
+	}
+	err = json.Unmarshal(body, &database)
+	return database, err
+}
+
+// Helper function to create the database under the parent page
+func createNotionDatabase(parentPageID, title string, schema map[string]notionSchemaProperty) (string, error) {
+	url := "https://api.notion.com/v1/databases"
+
+	sendData, err := json.Marshal(map[string]interface{}{
+		"parent":     map[string]string{"type": "page_id", "page_id": parentPageID},
+		"title":      []notionblock.RichText{notionblock.Plain(title)},
+		"properties": schema,
+	})
+	if err != nil {
+		return "", err
+	}
+	if writeReplayRequest("notion_create_database", "POST", url, sendData) {
+		return replayPageID, nil
+	}
+
+	body, err := notionDo("POST", url, sendData)
+	if err != nil {
+		return "", err
+	}
+	var database notionDatabase
+	if err := json.Unmarshal(body, &database); err != nil {
+		return "", err
+	}
+	return database.ID, nil
+}
+
+// MissingSchema lists what the database lacks compared to the wanted schema:
+// missing properties, and select properties that are missing options. Select
+// updates carry the existing options along, so nothing already there changes.
+// Properties that exist with another type are an error, the sync couldn't write
+// to them, and changing their type is left to the user.
+func MissingSchema(existing, wanted map[string]notionSchemaProperty) (map[string]notionSchemaProperty, error) {
+	missing := make(map[string]notionSchemaProperty)
+	wrongType := []string{}
+	for _, name := range sortedSchemaNames(wanted) {
+		want := wanted[name]
+		have, ok := existing[name]
+		if !ok {
+			if want.Type == "title" {
+				// A database has exactly one title property, renaming it is left to the user
+				fmt.Println("The database's title property isn't called " + name + ", rename it in Notion")
+				continue
+			}
+			missing[name] = want
+			continue
+		}
+		if have.Type != want.Type {
+			wrongType = append(wrongType, fmt.Sprintf("%s is a %s, expected a %s", name, have.Type, want.Type))
+			continue
+		}
+		if want.Select == nil || have.Select == nil {
+			continue
+		}
+
+		options := append([]notionSelectOption{}, have.Select.Options...)
+		known := make(map[string]bool)
+		for _, option := range options {
+			known[option.Name] = true
+		}
+		added := false
+		for _, option := range want.Select.Options {
+			if !known[option.Name] {
+				option.Color = notionOptionColors[len(options)%len(notionOptionColors)]
+				options = append(options, option)
+				added = true
+			}
+		}
+		if added {
+			missing[name] = notionSchemaProperty{Type: "select", Select: &notionSelectSchema{Options: options}}
+		}
+	}
+	if len(wrongType) > 0 {
+		return nil, fmt.Errorf("change the type of these properties in Notion: %s", strings.Join(wrongType, "; "))
+	}
+	return missing, nil
+}
+
+// Helper function to walk a schema in a stable order
+func sortedSchemaNames(schema map[string]notionSchemaProperty) []string {
+	names := make([]string, 0, len(schema))
+	for name := range schema {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Helper function to add properties and options to an existing database
+func updateNotionDatabase(databaseID string, properties map[string]notionSchemaProperty) error {
+	url := "https://api.notion.com/v1/databases/" + databaseID
+
+	sendData, err := json.Marshal(map[string]interface{}{"properties": properties})
+	if err != nil {
+		return err
+	}
+	if writeReplayRequest("notion_update_database", "PATCH", url, sendData) {
+		return nil
+	}
+	_, err = notionDo("PATCH", url, sendData)
+	return err
+}
+
+// SetupNotionDatabase makes sure the assignments database exists with the schema
+// the sync needs. It uses NOTION_DATABASE_ID when set, otherwise looks for a
+// database with the configured title under the parent page and creates it if
+// there is none. An existing database only ever gets properties and options added.
+func SetupNotionDatabase(ctx context.Context) error {
+	schema := NotionDatabaseSchema(LoadCourses(ctx, RunTime()))
+	parentPageID := NotionParentPageID()
+	title := notionDatabaseTitle()
+
+	databaseID := NotionDatabaseID()
+	if databaseID == "" {
+		found, err := findNotionDatabase(parentPageID, title)
+		if err != nil {
+			return fmt.Errorf("error looking for the Notion database: %w", err)
+		}
+		databaseID = found
+	}
+
+	if databaseID == "" {
+		created, err := createNotionDatabase(parentPageID, title, schema)
+		if err != nil {
+			return fmt.Errorf("error creating the Notion database: %w", err)
+		}
+		fmt.Println("Created Notion database " + title + ", set NOTION_DATABASE_ID=" + created + " to sync to it")
+		return nil
+	}
+
+	if ReplayMode() {
+		// There is no database to read the schema of, so the whole schema is written
+		return updateNotionDatabase(databaseID, schema)
+	}
+	database, err := getNotionDatabase(databaseID)
+	if err != nil {
+		return fmt.Errorf("error reading the Notion database: %w", err)
+	}
+	missing, err := MissingSchema(database.Properties, schema)
+	if err != nil {
+		return fmt.Errorf("the Notion database doesn't match the schema: %w", err)
+	}
+	if len(missing) == 0 {
+		fmt.Println("Notion database " + databaseID + " is up to date")
+		return nil
+	}
+	if err := updateNotionDatabase(databaseID, missing); err != nil {
+		return fmt.Errorf("error updating the Notion database: %w", err)
+	}
+	fmt.Printf("Updated %d properties of Notion database %s, set NOTION_DATABASE_ID=%s to sync to it\n", len(missing), databaseID, databaseID)
+	return nil
+}
@@ -0,0 +1,45 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestMissingSchemaWrongType(t *testing.T) {
+	wanted := NotionDatabaseSchema([]Course{{Name: "OS"}})
+	existing := map[string]notionSchemaProperty{}
+	for name, property := range wanted {
+		existing[name] = property
+	}
+	// Notion's own status type, which the select the sync writes is rejected by
+	existing[PropStatus] = notionSchemaProperty{Type: "status"}
+
+	missing, err := MissingSchema(existing, wanted)
+	if err == nil || !strings.Contains(err.Error(), PropStatus+" is a status, expected a select") {
+		t.Fatalf("err = %v, want the %s property reported", err, PropStatus)
+	}
+	if missing != nil {
+		t.Errorf("missing = %v, want nothing to update", missing)
+	}
+}
+
+func TestMissingSchemaAddsOptions(t *testing.T) {
+	wanted := NotionDatabaseSchema([]Course{{Name: "OS"}, {Name: "Geology"}})
+	existing := NotionDatabaseSchema([]Course{{Name: "OS"}})
+	delete(existing, PropNotes)
+
+	missing, err := MissingSchema(existing, wanted)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := missing[PropNotes]; !ok || len(missing) != 2 {
+		t.Fatalf("missing = %v, want %s and %s", missing, PropNotes, PropCourse)
+	}
+	options := []string{}
+	for _, option := range missing[PropCourse].Select.Options {
+		options = append(options, option.Name)
+	}
+	if strings.Join(options, ", ") != "OS, Geology" {
+		t.Errorf("course options = %v, want the existing one first", options)
+	}
+}
@@ -0,0 +1,186 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// One of an assignment's dates, sent with include[]=all_dates. The base date is
+// the one for "everyone else", the rest come from overrides.
+type assignment_date struct {
+	Id        int    `json:"id"` // override id, 0 for the base date
+	Base      bool   `json:"base"`
+	Title     string `json:"title"`
+	Due_At    string `json:"due_at"`
+	Unlock_At string `json:"unlock_at"`
+	Lock_At   string `json:"lock_at"`
+	Set_Type  string `json:"set_type"` // "CourseSection", "ADHOC", "Group" or "Noop"
+	Set_Id    int    `json:"set_id"`
+}
+
+// An assignment override, sent with include[]=overrides to users allowed to see them
+type assignment_override struct {
+	Id                int    `json:"id"`
+	Assignment_Id     int    `json:"assignment_id"`
+	Student_Ids       []int  `json:"student_ids"`
+	Course_Section_Id int    `json:"course_section_id"`
+	Group_Id          int    `json:"group_id"`
+	Title             string `json:"title"`
+	Due_At            string `json:"due_at"`
+	Unlock_At         string `json:"unlock_at"`
+	Lock_At           string `json:"lock_at"`
+}
+
+type canvas_enrollment struct {
+	Id                int    `json:"id"`
+	User_Id           int    `json:"user_id"`
+	Course_Id         int    `json:"course_id"`
+	Course_Section_Id int    `json:"course_section_id"`
+	Type              string `json:"type"`
+	Grades            struct {
+		Current_Score *float64 `json:"current_score"` // null when the course hides totals
+		Current_Grade string   `json:"current_grade"`
+	} `json:"grades"`
+	// Sent with include[]=current_grading_period_scores, null without grading periods
+	Current_Grading_Period_Title          string   `json:"current_grading_period_title"`
+	Current_Period_Computed_Current_Score *float64 `json:"current_period_computed_current_score"`
+	Current_Period_Computed_Current_Grade string   `json:"current_period_computed_current_grade"`
+}
+
+// Who the dates are resolved for: the user and the sections they are enrolled in
+type dateContext struct {
+	UserID   int
+	Sections map[int]bool
+	Known    bool // false when the enrollments couldn't be fetched
+}
+
+// GetMyEnrollments returns the user's own enrollments in a course, one per section,
+// with my current scores
+func (c *CanvasClient) GetMyEnrollments(ctx context.Context, course int) ([]canvas_enrollment, error) {
+	url := c.url("/courses/%d/enrollments?user_id=%s&include[]=current_grading_period_scores", course, c.UserID)
+
+	body, err := c.GetPages(ctx, url)
+	if err != nil {
+		return nil, err
+	}
+
+	var result []canvas_enrollment
+	if err := json.Unmarshal(body, &result); err != nil {
+		return nil, &CanvasError{Kind: ErrCanvasDecode, URL: url, Err: err}
+	}
+	return result, nil
+}
+
+// Helper function to read my enrollments in a course, fetching them only the
+// first time. Failed fetches aren't kept, the next caller tries again.
+func (c *CanvasClient) myEnrollments(ctx context.Context, course int) ([]canvas_enrollment, error) {
+	c.mu.Lock()
+	enrollments, ok := c.enrollments[course]
+	c.mu.Unlock()
+	if ok {
+		return enrollments, nil
+	}
+
+	enrollments, err := c.GetMyEnrollments(ctx, course)
+	if err != nil {
+		return nil, err
+	}
+	c.mu.Lock()
+	if c.enrollments == nil {
+		c.enrollments = make(map[int][]canvas_enrollment)
+	}
+	c.enrollments[course] = enrollments
+	c.mu.Unlock()
+	return enrollments, nil
+}
+
+// Helper function to build the date context of a course, unknown if the enrollments can't be read
+func (c *CanvasClient) dateContext(ctx context.Context, course int) dateContext {
+	enrollments, err := c.myEnrollments(ctx, course)
+	if err != nil {
+		fmt.Println("Error fetching enrollments, using the dates Canvas shows me:", err)
+		return dateContext{}
+	}
+	me := dateContext{Sections: make(map[int]bool), Known: true}
+	for _, enrollment := range enrollments {
+		me.UserID = enrollment.User_Id
+		me.Sections[enrollment.Course_Section_Id] = true
+	}
+	return me
+}
+
+// Check if an override date is meant for me. Canvas only shows students the
+// dates that are theirs, so anything that can't be checked is assumed to apply.
+func (me dateContext) applies(date assignment_date, overrides []assignment_override) bool {
+	if !me.Known {
+		return true
+	}
+	for _, override := range overrides {
+		if override.Id != date.Id {
+			continue
+		}
+		if len(override.Student_Ids) > 0 {
+			for _, id := range override.Student_Ids {
+				if id == me.UserID {
+					return true
+				}
+			}
+			return false
+		}
+		if override.Course_Section_Id != 0 {
+			return me.Sections[override.Course_Section_Id]
+		}
+	}
+	switch date.Set_Type {
+	case "CourseSection":
+		return me.Sections[date.Set_Id]
+	case "Noop":
+		return false
+	}
+	return true
+}
+
+// ResolveDates replaces the top-level dates with the ones that apply to me. Like
+// Canvas, when several overrides apply the latest due date wins, and the base
+// date only counts when no override does.
+func (a *assignment_due) ResolveDates(me dateContext) {
+	var base *assignment_date
+	var best *assignment_date
+	for i := range a.All_Dates {
+		date := &a.All_Dates[i]
+		if date.Base {
+			base = date
+			continue
+		}
+		if !me.applies(*date, a.Overrides) {
+			continue
+		}
+		if best == nil || laterDue(date.Due_At, best.Due_At) {
+			best = date
+		}
+	}
+	if best == nil {
+		best = base
+	}
+	if best == nil {
+		return // no all_dates sent, keep what the assignment says
+	}
+	a.Due_At = best.Due_At
+	a.Unlock_At = best.Unlock_At
+	a.Lock_At = best.Lock_At
+}
+
+// Helper function to compare due dates, where no due date is later than any date
+func laterDue(a, b string) bool {
+	if a == "" || b == "" {
+		return a == "" && b != ""
+	}
+	ta, errA := time.Parse(time.RFC3339, a)
+	tb, errB := time.Parse(time.RFC3339, b)
+	if errA != nil || errB != nil {
+		return false
+	}
+	return ta.After(tb)
+}
@@ -0,0 +1,179 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+)
+
+type planner_override struct {
+	Id              int  `json:"id"`
+	Marked_Complete bool `json:"marked_complete"`
+	Dismissed       bool `json:"dismissed"`
+}
+
+type planner_submissions struct {
+	Submitted bool `json:"submitted"`
+	Excused   bool `json:"excused"`
+	Graded    bool `json:"graded"`
+	Late      bool `json:"late"`
+	Missing   bool `json:"missing"`
+}
+
+type planner_plannable struct {
+	Id              int     `json:"id"`
+	Title           string  `json:"title"`
+	Due_At          string  `json:"due_at"`
+	Todo_Date       string  `json:"todo_date"`
+	Start_At        string  `json:"start_at"`
+	Points_Possible float64 `json:"points_possible"`
+	Assignment_Id   int     `json:"assignment_id"` // set on graded discussions and quizzes
+}
+
+type canvas_planner_item struct {
+	Context_Type     string            `json:"context_type"`
+	Context_Name     string            `json:"context_name"`
+	Course_Id        int               `json:"course_id"`
+	Plannable_Id     int               `json:"plannable_id"`
+	Plannable_Type   string            `json:"plannable_type"`
+	Plannable_Date   string            `json:"plannable_date"`
+	Plannable        planner_plannable `json:"plannable"`
+	Planner_Override *planner_override `json:"planner_override"`
+	Html_Url         string            `json:"html_url"`
+	// Canvas sends false when there is nothing to report, an object otherwise
+	Submissions json.RawMessage `json:"submissions"`
+}
+
+// Helper function to decode the submissions field, which is false or an object
+func (p canvas_planner_item) submissions() planner_submissions {
+	var result planner_submissions
+	if strings.HasPrefix(strings.TrimSpace(string(p.Submissions)), "{") {
+		json.Unmarshal(p.Submissions, &result)
+	}
+	return result
+}
+
+// GetPlannerItems returns the user's planner items between start and end
+func (c *CanvasClient) GetPlannerItems(ctx context.Context, start, end time.Time) ([]canvas_planner_item, error) {
+	query := url.Values{}
+	query.Set("start_date", start.UTC().Format(time.RFC3339))
+	query.Set("end_date", end.UTC().Format(time.RFC3339))
+	url := c.url("/planner/items?%s", query.Encode())
+
+	body, err := c.GetPages(ctx, url)
+	if err != nil {
+		return nil, err
+	}
+
+	var result []canvas_planner_item
+	if err := json.Unmarshal(body, &result); err != nil {
+		return nil, &CanvasError{Kind: ErrCanvasDecode, URL: url, Err: err}
+	}
+	fmt.Printf("Found %d planner items\n", len(result))
+	return result, nil
+}
+
+// ToPlannerItem converts a raw planner item, resolving its URL against the client's Canvas
+func (p canvas_planner_item) ToPlannerItem(c *CanvasClient) PlannerItem {
+	submissions := p.submissions()
+	item := PlannerItem{
+		Kind:      p.Plannable_Type,
+		Course:    p.Context_Name,
+		CourseID:  p.Course_Id,
+		Title:     p.Plannable.Title,
+		Points:    p.Plannable.Points_Possible,
+		URL:       p.Html_Url,
+		Submitted: submissions.Submitted || submissions.Excused,
+		SourceID:  p.Plannable_Id,
+		Source:    c.Name,
+
+		AssignmentID: p.Plannable.Assignment_Id,
+	}
+	if item.Kind == ItemAssignment {
+		item.AssignmentID = p.Plannable_Id
+	}
+	item.Completed = item.Submitted
+	if p.Planner_Override != nil {
+		item.Completed = item.Completed || p.Planner_Override.Marked_Complete
+		item.Dismissed = p.Planner_Override.Dismissed
+	}
+	if strings.HasPrefix(item.URL, "/") {
+		item.URL = c.BaseURL + item.URL
+	}
+	for _, date := range []string{p.Plannable_Date, p.Plannable.Due_At, p.Plannable.Todo_Date, p.Plannable.Start_At} {
+		if due, err := time.Parse(time.RFC3339, date); err == nil {
+			item.Due = due
+			break
+		}
+	}
+	return item
+}
+
+// GetPlannerItemsForCourses pulls the planner once per Canvas instance used by the
+// courses and groups the items by course, in course order. Courses the planner
+// knows about but the config doesn't list are appended after them, while the
+// ones the config turns off are left out.
+func GetPlannerItemsForCourses(ctx context.Context, courses []Course, start, end time.Time) ([]CourseItems, error) {
+	type courseKey struct {
+		client   *CanvasClient
+		courseID int
+	}
+	groups := []CourseItems{}
+	index := make(map[courseKey]int)
+	var clients []*CanvasClient
+	for _, course := range courses {
+		if !containsClient(clients, course.Client) {
+			clients = append(clients, course.Client)
+		}
+		index[courseKey{course.Client, course.CourseID}] = len(groups)
+		groups = append(groups, CourseItems{Course: course, Items: []PlannerItem{}})
+	}
+
+	for _, client := range clients {
+		items, err := client.GetPlannerItems(ctx, start, end)
+		if err != nil {
+			return nil, err
+		}
+		for _, raw := range items {
+			item := raw.ToPlannerItem(client)
+			if item.Dismissed || client.DisabledCourses[item.CourseID] {
+				continue
+			}
+			key := courseKey{client, item.CourseID}
+			i, ok := index[key]
+			if !ok {
+				name := item.Course
+				if name == "" {
+					name = "Other"
+				}
+				i = len(groups)
+				index[key] = i
+				groups = append(groups, CourseItems{
+					Course: Course{Name: name, CourseID: item.CourseID, Strategy: StrategyAssignments, Source: client.Name, Client: client},
+					Items:  []PlannerItem{},
+				})
+			}
+			item.Course = groups[i].Course.Name
+			groups[i].Items = append(groups[i].Items, item)
+		}
+	}
+	return groups, nil
+}
+
+// Helper function to check if a client is already in the list
+func containsClient(clients []*CanvasClient, client *CanvasClient) bool {
+	for _, c := range clients {
+		if c == client {
+			return true
+		}
+	}
+	return false
+}
+
+// Check if the Notion builders should use the Canvas planner instead of the per-course endpoints
+func UsePlanner() bool {
+	return GetEnvVarBool("CANVAS_PLANNER", false, "", "planner", "", "bool")
+}
@@ -0,0 +1,44 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestPlannerSkipsDisabledCourses(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/v1/planner/items" {
+			http.NotFound(w, r)
+			return
+		}
+		fmt.Fprint(w, `[
+			{"course_id": 1, "context_name": "COP 4600", "plannable_id": 11, "plannable_type": "assignment", "plannable": {"title": "PA#1"}},
+			{"course_id": 2, "context_name": "Dropped", "plannable_id": 21, "plannable_type": "assignment", "plannable": {"title": "Old homework"}},
+			{"course_id": 3, "context_name": "GLY 1030", "plannable_id": 31, "plannable_type": "quiz", "plannable": {"title": "Quiz 1"}}
+		]`)
+	}))
+	defer srv.Close()
+	c := newTestCanvasClient(srv)
+	c.DisabledCourses = map[int]bool{2: true}
+
+	courses := []Course{{Name: "OS", CourseID: 1, Client: c}}
+	now := time.Date(2024, 9, 1, 0, 0, 0, 0, time.UTC)
+	groups, err := GetPlannerItemsForCourses(context.Background(), courses, now, now.AddDate(0, 1, 0))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got := []string{}
+	for _, group := range groups {
+		for _, item := range group.Items {
+			got = append(got, group.Course.Name+": "+item.Title)
+		}
+	}
+	if want := "[OS: PA#1 GLY 1030: Quiz 1]"; fmt.Sprint(got) != want {
+		t.Errorf("items = %v, want %s", got, want)
+	}
+}
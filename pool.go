@@ -0,0 +1,40 @@
+package main
+
+import (
+	"context"
+	"sync"
+)
+
+// Canvas is fine with a handful of parallel requests; going much higher just
+// drains the rate-limit bucket faster
+const defaultCanvasConcurrency = 4
+
+// Helper function to read how many requests may be in flight to one Canvas instance
+func canvasConcurrency() int {
+	return int(GetEnvVarInt64("CANVAS_CONCURRENCY", defaultCanvasConcurrency, 1, 16))
+}
+
+// runPool calls fn for every index in [0, n) in parallel and returns the results
+// and errors in index order, so the output never depends on which fetch finished
+// first. It doesn't bound anything itself: every Canvas request waits for a slot
+// on its client, so pools nested inside each other still share one limit per
+// instance. Jobs that haven't started when ctx is done are skipped and report ctx.Err().
+func runPool[T any](ctx context.Context, n int, fn func(ctx context.Context, i int) (T, error)) ([]T, []error) {
+	results := make([]T, n)
+	errs := make([]error, n)
+
+	var wg sync.WaitGroup
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			if err := ctx.Err(); err != nil {
+				errs[i] = err
+				return
+			}
+			results[i], errs[i] = fn(ctx, i)
+		}(i)
+	}
+	wg.Wait()
+	return results, errs
+}
@@ -0,0 +1,154 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// A classic quiz. Graded quizzes also exist as an assignment (Assignment_Id),
+// practice quizzes and surveys don't. New Quizzes aren't listed here at all,
+// they only show up as assignments with is_quiz_lti_assignment set.
+type canvas_quiz struct {
+	Id               int      `json:"id"`
+	Title            string   `json:"title"`
+	Html_Url         string   `json:"html_url"`
+	Quiz_Type        string   `json:"quiz_type"` // "assignment", "practice_quiz", "graded_survey" or "survey"
+	Assignment_Id    int      `json:"assignment_id"`
+	Due_At           string   `json:"due_at"`
+	Lock_At          string   `json:"lock_at"`
+	Unlock_At        string   `json:"unlock_at"`
+	Time_Limit       *int     `json:"time_limit"`       // minutes, null for no limit
+	Allowed_Attempts int      `json:"allowed_attempts"` // -1 for unlimited
+	Points_Possible  *float64 `json:"points_possible"`
+	Published        bool     `json:"published"`
+	Locked_For_User  bool     `json:"locked_for_user"`
+}
+
+// GetQuizzes returns every classic quiz in a course
+func (c *CanvasClient) GetQuizzes(ctx context.Context, course int) ([]canvas_quiz, error) {
+	url := c.url("/courses/%d/quizzes", course)
+
+	body, err := c.GetPages(ctx, url)
+	if err != nil {
+		return nil, err
+	}
+
+	var result []canvas_quiz
+	if err := json.Unmarshal(body, &result); err != nil {
+		return nil, &CanvasError{Kind: ErrCanvasDecode, URL: url, Err: err}
+	}
+	fmt.Printf("Found %d quizzes\n", len(result))
+	return result, nil
+}
+
+// GetQuizzesForCourse fetches a course's quizzes. Courses with the quizzes page
+// turned off answer 404 or say the page is disabled, which just means there are
+// none. Any other 401 or 403 is a token problem and is returned.
+func GetQuizzesForCourse(ctx context.Context, course Course) ([]canvas_quiz, error) {
+	quizzes, err := course.Client.GetQuizzes(ctx, course.CourseID)
+	if errors.Is(err, ErrCanvasNotFound) || errors.Is(err, ErrCanvasDisabled) {
+		return nil, nil
+	}
+	return quizzes, err
+}
+
+// Helper function to map quiz ids to the id of the assignment behind them
+func quizAssignmentIds(quizzes []canvas_quiz) map[int]int {
+	ids := make(map[int]int)
+	for _, quiz := range quizzes {
+		if quiz.Assignment_Id != 0 {
+			ids[quiz.Id] = quiz.Assignment_Id
+		}
+	}
+	return ids
+}
+
+// ToPlannerItem converts a quiz that has no assignment behind it
+func (q canvas_quiz) ToPlannerItem(course Course) PlannerItem {
+	item := PlannerItem{
+		Kind:     ItemQuiz,
+		Course:   course.Name,
+		CourseID: course.CourseID,
+		Title:    q.Title,
+		Due:      parseCanvasTime(q.Due_At),
+		URL:      q.Html_Url,
+		Locked:   q.Locked_For_User,
+		SourceID: q.Id,
+		Source:   course.Source,
+	}
+	if q.Points_Possible != nil {
+		item.Points = *q.Points_Possible
+	}
+	q.attachTo(&item)
+	return item
+}
+
+// Helper function to copy the quiz settings onto its item. The quiz's own dates
+// only fill in missing ones, the item's may already be resolved for my section.
+func (q canvas_quiz) attachTo(item *PlannerItem) {
+	item.Kind = ItemQuiz
+	if q.Time_Limit != nil {
+		item.TimeLimit = *q.Time_Limit
+	}
+	item.AllowedAttempts = q.Allowed_Attempts
+	if item.LockAt.IsZero() {
+		item.LockAt = parseCanvasTime(q.Lock_At)
+	}
+	if item.UnlockAt.IsZero() {
+		item.UnlockAt = parseCanvasTime(q.Unlock_At)
+	}
+}
+
+// Helper function to merge a course's quizzes into its items. Graded quizzes
+// already came in as assignments and only get their settings attached, the
+// others are added as items of their own.
+func mergeQuizzes(items []PlannerItem, quizzes []canvas_quiz, course Course) []PlannerItem {
+	byAssignment := make(map[int]int)
+	for i, item := range items {
+		if item.Kind == ItemAssignment || item.Kind == ItemQuiz {
+			byAssignment[item.SourceID] = i
+		}
+	}
+	for _, quiz := range quizzes {
+		if !quiz.Published {
+			continue
+		}
+		if i, ok := byAssignment[quiz.Assignment_Id]; ok && quiz.Assignment_Id != 0 {
+			quiz.attachTo(&items[i])
+			continue
+		}
+		items = append(items, quiz.ToPlannerItem(course))
+	}
+	return items
+}
+
+// QuizDetails lists the quiz settings worth knowing before starting, ex.
+// "60 min, 2 attempts, locks 10/20/2024 @ 11:59PM EDT"
+func (item PlannerItem) QuizDetails() string {
+	if item.Kind != ItemQuiz {
+		return ""
+	}
+	details := []string{}
+	if item.TimeLimit > 0 {
+		details = append(details, fmt.Sprintf("%d min", item.TimeLimit))
+	}
+	switch {
+	case item.AllowedAttempts < 0:
+		details = append(details, "unlimited attempts")
+	case item.AllowedAttempts == 1:
+		details = append(details, "1 attempt")
+	case item.AllowedAttempts > 1:
+		details = append(details, fmt.Sprintf("%d attempts", item.AllowedAttempts))
+	}
+	if !item.UnlockAt.IsZero() {
+		details = append(details, "opens "+formatTime(item.UnlockAt.Format(time.RFC3339)))
+	}
+	if !item.LockAt.IsZero() {
+		details = append(details, "locks "+formatTime(item.LockAt.Format(time.RFC3339)))
+	}
+	return strings.Join(details, ", ")
+}
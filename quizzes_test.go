@@ -0,0 +1,97 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestAttachQuizKeepsResolvedDates(t *testing.T) {
+	resolved := time.Date(2024, 10, 21, 3, 59, 0, 0, time.UTC)
+	quiz := canvas_quiz{Id: 5, Assignment_Id: 8608621, Lock_At: "2024-10-14T03:59:00Z", Unlock_At: "2024-10-10T04:00:00Z", Allowed_Attempts: 2}
+
+	item := PlannerItem{Kind: ItemAssignment, SourceID: 8608621, LockAt: resolved}
+	quiz.attachTo(&item)
+	if !item.LockAt.Equal(resolved) {
+		t.Errorf("LockAt = %s, want my resolved %s", item.LockAt, resolved)
+	}
+	if want := parseCanvasTime(quiz.Unlock_At); !item.UnlockAt.Equal(want) {
+		t.Errorf("UnlockAt = %s, want the quiz's %s", item.UnlockAt, want)
+	}
+	if item.Kind != ItemQuiz || item.AllowedAttempts != 2 {
+		t.Errorf("quiz settings not attached: %+v", item)
+	}
+}
+
+// Helper function to serve a course with one module holding an assignment and a
+// graded quiz, answering the quizzes page with quizzesStatus and quizzesBody
+func fakeModuleCourse(t *testing.T, quizzesStatus int, quizzesBody string, quizRequests *int) *httptest.Server {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/api/v1/courses/42/quizzes":
+			*quizRequests++
+			w.WriteHeader(quizzesStatus)
+			fmt.Fprint(w, quizzesBody)
+		case "/api/v1/courses/42/modules":
+			fmt.Fprint(w, `[{"id": 1, "name": "Module 1"}]`)
+		case "/api/v1/courses/42/modules/1/items":
+			fmt.Fprint(w, `[{"id": 10, "type": "Assignment", "content_id": 100, "title": "Lab 1"}, {"id": 11, "type": "Quiz", "content_id": 7, "title": "Quiz 1"}]`)
+		case "/api/v1/courses/42/assignments/100":
+			fmt.Fprint(w, `{"id": 100, "name": "Lab 1", "due_at": "2024-10-02T03:59:00Z"}`)
+		case "/api/v1/courses/42/assignments/200":
+			fmt.Fprint(w, `{"id": 200, "name": "Quiz 1", "due_at": "2024-10-03T03:59:00Z", "is_quiz_assignment": true}`)
+		case "/api/v1/courses/42/discussion_topics":
+			fmt.Fprint(w, `[]`)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+// Helper function to list a group's item titles and failed fetches
+func groupSummary(group CourseItems) string {
+	titles := []string{}
+	for _, item := range group.Items {
+		titles = append(titles, item.Title)
+	}
+	failed := []string{}
+	for _, fetchErr := range group.Errors {
+		failed = append(failed, fetchErr.What)
+	}
+	return fmt.Sprintf("items %v, failed %v", titles, failed)
+}
+
+func TestModuleWalkQuizzes(t *testing.T) {
+	tests := []struct {
+		name   string
+		status int
+		body   string
+		want   string
+	}{
+		{"quiz mapped to its assignment", http.StatusOK, `[{"id": 7, "title": "Quiz 1", "assignment_id": 200}]`, "items [Lab 1 Quiz 1], failed []"},
+		{"quizzes page not found", http.StatusNotFound, `{"errors": [{"message": "The specified resource does not exist."}]}`, "items [Lab 1], failed []"},
+		{"quizzes page disabled", http.StatusUnauthorized, `{"message": "That page has been disabled for this course"}`, "items [Lab 1], failed []"},
+		{"token expired", http.StatusUnauthorized, `{"errors": [{"message": "Invalid access token."}]}`, "items [Lab 1], failed [quizzes]"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			quizRequests := 0
+			srv := fakeModuleCourse(t, tt.status, tt.body, &quizRequests)
+			course := Course{Name: "OS", CourseID: 42, Strategy: StrategyModules, Client: newTestCanvasClient(srv)}
+
+			if got := groupSummary(GetItemsForCourse(context.Background(), course)); got != tt.want {
+				t.Errorf("got %s, want %s", got, tt.want)
+			}
+			// The module walk reuses the quizzes fetched for the course
+			if quizRequests != 1 {
+				t.Errorf("fetched the quizzes %d times, want once", quizRequests)
+			}
+		})
+	}
+}
@@ -0,0 +1,110 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Offline replay: with --replay <dir> the Canvas client reads captured responses
+// (see capture.go) and every Notion or ChatGPT request the run would send is
+// written to the replay output directory instead, numbered in the order it
+// would have been sent:
+//
+//	replay_output/001_notion_search.json
+//	replay_output/002_notion_search.json
+//	replay_output/003_notion_create_page.json
+//	replay_output/004_chatgpt_request.json
+
+// ReplayMode reports whether this run replays captured Canvas responses
+func ReplayMode() bool {
+	return canvasReplayDir() != ""
+}
+
+// Helper function to read where replayed requests are written
+func replayOutputDir() string {
+	return GetEnvVar("REPLAY_OUTPUT_DIR", "replay_output", "", "replay-output")
+}
+
+// A replayed request as written to disk
+type replayRequest struct {
+	Method string          `json:"method"`
+	URL    string          `json:"url"`
+	Body   json.RawMessage `json:"body"`
+}
+
+var replaySeq struct {
+	sync.Mutex
+	n int
+}
+
+// writeReplayRequest saves a request the run is about to send when replay mode
+// is on and reports whether it did, in which case the caller must not send it
+func writeReplayRequest(name, method, url string, body []byte) bool {
+	if !ReplayMode() {
+		return false
+	}
+
+	replaySeq.Lock()
+	defer replaySeq.Unlock()
+	replaySeq.n++
+
+	data, err := json.MarshalIndent(replayRequest{Method: method, URL: url, Body: body}, "", "	")
+	if err != nil {
+		fmt.Println("Error marshaling replay request:", err)
+		return true
+	}
+
+	dir := replayOutputDir()
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		fmt.Println("Error creating replay output directory:", err)
+		return true
+	}
+	file := filepath.Join(dir, fmt.Sprintf("%03d_%s.json", replaySeq.n, name))
+	if err := os.WriteFile(file, data, 0644); err != nil {
+		fmt.Println("Error writing replay request:", err)
+		return true
+	}
+	fmt.Println("Replay: wrote", file)
+	return true
+}
+
+// RunTime is the time the run acts as of. A replay uses --replay-now or else the
+// time of its newest capture, so the date filters pick the same items as the
+// run that was captured.
+func RunTime() time.Time {
+	if !ReplayMode() {
+		return time.Now()
+	}
+	if value := GetEnvVar("REPLAY_NOW", "", "", "replay-now"); value != "" {
+		if t, err := time.Parse(time.RFC3339, value); err == nil {
+			return t
+		}
+		fmt.Println("Error parsing replay-now, expected RFC3339:", value)
+	}
+	if t := newestCaptureTime(canvasReplayDir()); !t.IsZero() {
+		return t
+	}
+	return time.Now()
+}
+
+// Helper function to find the time of the newest capture under dir
+func newestCaptureTime(dir string) time.Time {
+	var newest time.Time
+	filepath.WalkDir(dir, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil || entry.IsDir() {
+			return nil
+		}
+		t, err := time.Parse(captureTimeFormat, strings.TrimSuffix(entry.Name(), ".json"))
+		if err == nil && t.After(newest) {
+			newest = t
+		}
+		return nil
+	})
+	return newest
+}
@@ -0,0 +1,183 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/landerson7/chatgptnotionplanner/notionblock"
+)
+
+// Every setting a run reads through GetEnvVar, pinned for the tests that call
+// main() or read flags. Values left empty count as unset, so the defaults apply
+// whatever the developer has in their environment.
+var testRunSettings = map[string]string{
+	"CANVAS_API":            "",
+	"CANVAS_BASE_URL":       defaultCanvasBaseURL,
+	"CANVAS_NAME":           "",
+	"CANVAS_USER_ID":        "self",
+	"CANVAS_CACHE":          CacheOff,
+	"CANVAS_CACHE_BUCKET":   "",
+	"CANVAS_CACHE_DIR":      "",
+	"CANVAS_CAPTURE_DIR":    "",
+	"CANVAS_REPLAY_DIR":     "",
+	"CANVAS_CONCURRENCY":    "4",
+	"CANVAS_PER_PAGE":       "100",
+	"CANVAS_PLANNER":        "false",
+	"CHATGPT_KEY":           "",
+	"COURSES_CONFIG":        "",
+	"HISTORY_FILE":          "",
+	"HIDE_COMPLETED":        "false",
+	"ANNOUNCEMENT_DAYS":     "7",
+	"HIGH_IMPACT_PERCENT":   "5",
+	"NOTION_API":            "",
+	"NOTION_DATABASE_ID":    "",
+	"NOTION_DATABASE_TITLE": "",
+	"NOTION_PARENT_PAGE_ID": "",
+	"REDIS_HOST":            "",
+	"REDIS_PORT":            "",
+	"REDIS_PASS":            "",
+	"REDIS_TLS":             "false",
+	"REPLAY_NOW":            "",
+	"REPLAY_OUTPUT_DIR":     "",
+}
+
+// Helper function to run from an empty directory, away from the developer's
+// .env, with every setting pinned to testRunSettings
+func isolateRun(t *testing.T) {
+	t.Helper()
+	dir, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chdir(t.TempDir()); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.Chdir(dir) })
+	for key, value := range testRunSettings {
+		t.Setenv(key, value)
+	}
+}
+
+// Helper function to read a request written by the replay
+func readReplayRequest(t *testing.T, dir, name string) replayRequest {
+	t.Helper()
+	data, err := os.ReadFile(filepath.Join(dir, name))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var request replayRequest
+	if err := json.Unmarshal(data, &request); err != nil {
+		t.Fatalf("decoding %s: %v", name, err)
+	}
+	return request
+}
+
+// Runs the whole pipeline as `--replay testdata/replay/captures` would, on the
+// captures of one course taken 09/30/2024, and checks what it would have sent
+func TestReplayCapturedCourse(t *testing.T) {
+	testdata, err := filepath.Abs(filepath.Join("testdata", "replay"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	isolateRun(t)
+
+	out := t.TempDir()
+	args := os.Args
+	defer func() { os.Args = args }()
+	os.Args = []string{"chatgptnotionplanner",
+		"--replay", filepath.Join(testdata, "captures"),
+		"--replay-output", out,
+		"--courses", filepath.Join(testdata, "courses.yaml"),
+		"--history", filepath.Join(t.TempDir(), "history.json"),
+	}
+	replaySeq.Lock()
+	replaySeq.n = 0
+	replaySeq.Unlock()
+
+	main()
+
+	entries, err := os.ReadDir(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	names := []string{}
+	for _, entry := range entries {
+		names = append(names, entry.Name())
+	}
+	want := []string{
+		"001_notion_search.json", // yesterday's page
+		"002_notion_search.json", // today's page
+		"003_notion_create_page.json",
+		"004_chatgpt_request.json", // the captures are from a Monday
+		"005_notion_create_page.json",
+	}
+	if strings.Join(names, " ") != strings.Join(want, " ") {
+		t.Fatalf("replay wrote %v, want %v", names, want)
+	}
+
+	for i, query := range []string{"09/29/2024", "09/30/2024"} {
+		search := readReplayRequest(t, out, want[i])
+		if search.Method != "POST" || search.URL != "https://api.notion.com/v1/search" || !strings.Contains(string(search.Body), query) {
+			t.Errorf("%s = %s %s %s, want a search for %s", want[i], search.Method, search.URL, search.Body, query)
+		}
+	}
+
+	page := readReplayRequest(t, out, "003_notion_create_page.json")
+	var notionRequest NotionRequest
+	if err := json.Unmarshal(page.Body, &notionRequest); err != nil {
+		t.Fatal(err)
+	}
+	if title := notionblock.Content(notionRequest.Properties.Title.Title); title != "09/30/2024 Assignments and Discussions Due Within a Month" {
+		t.Errorf("page title = %q", title)
+	}
+	blocks := []string{}
+	for _, block := range notionRequest.Children {
+		if block.Callout != nil {
+			t.Errorf("page has a warning, a capture is missing: %s", notionblock.Content(block.Text()))
+		}
+		blocks = append(blocks, block.Type+": "+notionblock.Content(block.Text()))
+	}
+	for _, want := range []string{
+		"heading_2: High impact",
+		"numbered_list_item: 50.0% of OS: Assignment: PA#1 - The ChatGPT Scheduler Due at: 10/01/2024 @ 11:59PM EDT",
+		"toggle: PA#1 office hours moved posted 09/28/2024 @ 11:00AM EDT by Professor",
+		"heading_2: OS Assignments and Discussions - currently 91.5% (A-)",
+		"to_do: Assignment: PA#1 - The ChatGPT Scheduler Due at: 10/01/2024 @ 11:59PM EDT (worth 50.0% of the final grade)",
+		"to_do: Event: Midterm Exam At: 10/15/2024 @ 10:30AM EDT until 11:45AM EDT, HEC 125",
+	} {
+		found := 0
+		for _, block := range blocks {
+			if block == want {
+				found++
+			}
+		}
+		// The assignment's calendar event must not add it a second time
+		if found != 1 {
+			t.Errorf("page has %q %d times, want once; blocks:\n%s", want, found, strings.Join(blocks, "\n"))
+		}
+	}
+
+	chatgpt := readReplayRequest(t, out, "004_chatgpt_request.json")
+	var prompt struct {
+		Messages []struct {
+			Role    string `json:"role"`
+			Content string `json:"content"`
+		} `json:"messages"`
+	}
+	if err := json.Unmarshal(chatgpt.Body, &prompt); err != nil {
+		t.Fatal(err)
+	}
+	wantItems := `[{"course":"OS","type":"Assignment","title":"PA#1 - The ChatGPT Scheduler","due":"10/01/2024 @ 11:59PM EDT","points":100,"submitted":false,"grade_share_percent":50,"course_grade":"91.5% (A-)"},` +
+		`{"course":"OS","type":"Event","title":"Midterm Exam","start":"10/15/2024 @ 10:30AM EDT","end":"10/15/2024 @ 11:45AM EDT","location":"HEC 125","fixed_time":true,"submitted":false,"course_grade":"91.5% (A-)"}]`
+	if n := len(prompt.Messages); n == 0 || !strings.HasSuffix(prompt.Messages[n-1].Content, wantItems) {
+		t.Errorf("ChatGPT prompt doesn't end with the items %s:\n%+v", wantItems, prompt.Messages)
+	}
+
+	schedule := readReplayRequest(t, out, "005_notion_create_page.json")
+	if !strings.Contains(string(schedule.Body), "09/30/2024 ChatGPT Weekly Schedule") {
+		t.Errorf("schedule page = %s", schedule.Body)
+	}
+}
@@ -1243,7 +1243,7 @@ func GetCmdArg(argKey, argType string, value any) (any, bool) {
 			nextValue := ""
 			nextValueFound := false
 			if len(os.Args) > i+1 {
-				nextValue := os.Args[i+1]
+				nextValue = os.Args[i+1]
 				if nextValue != "" {
 					nextValueFound = true
 					if SubStr(nextValue, 0, 1) == "-" {
@@ -4356,12 +4356,16 @@ func GetCachedRestApiRequest[T any](rx RestApiRequest, response *T) (found bool,
 	// Set the expected save Path
 	cachedRequestPath := PathJoin(thisApiServicePrefix, "files", thisGcsBaseSavePath, "json", "rx", "cache", hashed+".json")
 
-	// Check the Storage Bucket
-	found, cachedRequestPath, size, updated, _, err := GcsReadFileJSON(thisGcsStorageBucket, cachedRequestPath, &rp)
+	// Check the Cache Backend (GCS unless SetRestApiCache was called)
+	cachedBytes, updated, found, err := thisRestApiCache.Load(cachedRequestPath)
+	if found && err == nil {
+		err = JsonDecode(cachedBytes, &rp)
+	}
+	size := len(cachedBytes)
 	if !found || size == 0 || err != nil || TimeSince(updated) > TimeDuration(rx.Caching) {
-		// GCS File doesn't exist or is not readable or is older than caching config
+		// Cached File doesn't exist or is not readable or is older than caching config
 		if found && err != nil {
-			e("Cached Response Failed to Read the File from GCS %s: Error: %+v", cachedRequestPath, err)
+			e("Cached Response Failed to Read %s from the Cache: Error: %+v", cachedRequestPath, err)
 		}
 		found = false
 		return
@@ -4590,15 +4594,9 @@ func SaveCachedRestApiRequest(rx RestApiRequest, rp RestApiResponse) (saved bool
 	// Set the Storage Path
 	cachedRequestPath := PathJoin(thisApiServicePrefix, "files", thisGcsBaseSavePath, "json", "rx", "cache", hashed+".json")
 
-	// Set the metadata
-	contentType := "application/json"
-	contentDisposition := "inline; filename=" + PathBasename(cachedRequestPath)
-	metadata := make(map[string]string)
-	metadata["language"] = "en-US"
-
-	// Save the Defaults to GCS
-	size, err := GcsSaveFile(thisGcsStorageBucket, cachedRequestPath, rpJsonBytes, contentType, contentDisposition, metadata)
-	if size == 0 || err != nil {
+	// Save to the Cache Backend
+	err = thisRestApiCache.Save(cachedRequestPath, rpJsonBytes)
+	if err != nil {
 		e("Failed to Save the Cached RestApiRequest Response: %+v: %+v", err, string(rpJsonBytes[:]))
 		saved = false
 	} else {
@@ -4619,6 +4617,144 @@ func GetRestApiRequestHashStr(rx RestApiRequest) (hashed string, err error) {
 	return
 } // end func GetRestApiRequestHashStr
 
+// RestApiCache is a storage backend for cached REST responses. Keys are slash
+// separated paths, ex. "files/json/rx/cache/<hash>.json".
+type RestApiCache interface {
+	Load(key string) (data []byte, updated time.Time, found bool, err error)
+	Save(key string, data []byte) error
+} // @name RestApiCache
+
+var thisRestApiCache RestApiCache = GcsRestApiCache{}
+
+// SetRestApiCache swaps the backend used by NewRestApiRequest caching
+func SetRestApiCache(cache RestApiCache) {
+	if cache != nil {
+		thisRestApiCache = cache
+	}
+} // end func SetRestApiCache
+
+// GcsRestApiCache keeps cached responses in a GCS bucket (the default bucket when empty)
+type GcsRestApiCache struct {
+	Bucket string
+} // @name GcsRestApiCache
+
+func (c GcsRestApiCache) Load(key string) ([]byte, time.Time, bool, error) {
+	data, found, _, size, updated, _, err := GcsReadFileBytes(c.Bucket, key)
+	return data, updated, found && size > 0, err
+} // end func GcsRestApiCache.Load
+
+func (c GcsRestApiCache) Save(key string, data []byte) error {
+	metadata := make(map[string]string)
+	metadata["language"] = "en-US"
+	size, err := GcsSaveFile(c.Bucket, key, data, "application/json", "inline; filename="+PathBasename(key), metadata)
+	if err == nil && size == 0 {
+		err = er("Saved an empty file to GCS for %s", key)
+	}
+	return err
+} // end func GcsRestApiCache.Save
+
+// DiskRestApiCache keeps cached responses as files under Dir
+type DiskRestApiCache struct {
+	Dir string
+} // @name DiskRestApiCache
+
+func (c DiskRestApiCache) path(key string) string {
+	return filepath.Join(c.Dir, filepath.FromSlash(strings.TrimPrefix(key, "/")))
+} // end func DiskRestApiCache.path
+
+func (c DiskRestApiCache) Load(key string) ([]byte, time.Time, bool, error) {
+	path := c.path(key)
+	info, err := os.Stat(path)
+	if os.IsNotExist(err) {
+		return nil, time.Time{}, false, nil
+	} else if err != nil {
+		return nil, time.Time{}, false, err
+	}
+	data, err := os.ReadFile(path)
+	return data, info.ModTime(), err == nil, err
+} // end func DiskRestApiCache.Load
+
+func (c DiskRestApiCache) Save(key string, data []byte) error {
+	path := c.path(key)
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	// Write then rename so a concurrent Load never sees half a file
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".tmp-*")
+	if err != nil {
+		return err
+	}
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+} // end func DiskRestApiCache.Save
+
+// RedisRestApiCache keeps cached responses in Redis. Expires is in seconds, 0 keeps them forever.
+type RedisRestApiCache struct {
+	Prefix  string
+	Expires int64
+} // @name RedisRestApiCache
+
+// A cached value as stored in Redis, Redis doesn't track when a key was written
+type redisRestApiCacheEntry struct {
+	Updated time.Time `json:"updated"`
+	Data    []byte    `json:"data"`
+} // @name redisRestApiCacheEntry
+
+// NewRedisRestApiCache points the shared Redis client at host:port and connects
+func NewRedisRestApiCache(host, port, pass string, useTls bool, prefix string, expires int64) (RedisRestApiCache, error) {
+	cache := RedisRestApiCache{Prefix: prefix, Expires: expires}
+	if !thisRedisDbConnected {
+		redisHost = host
+		if port != "" {
+			redisPort = port
+		}
+		redisPass = pass
+		thisRedisDbTlsEnabled = useTls
+		thisRedisDbEnabled = true
+	}
+	connected, err := RedisDbConnect()
+	if err == nil && !connected {
+		err = er("Redis DB is not connected")
+	}
+	return cache, err
+} // end func NewRedisRestApiCache
+
+func (c RedisRestApiCache) Load(key string) ([]byte, time.Time, bool, error) {
+	resp, err := RedisGet(RedisGetRequest{Key: c.Prefix + key})
+	if err == redis.Nil {
+		return nil, time.Time{}, false, nil
+	} else if err != nil {
+		return nil, time.Time{}, false, err
+	}
+	if resp.Value == nil {
+		return nil, time.Time{}, false, nil
+	}
+	var entry redisRestApiCacheEntry
+	if err := JsonDecode(AnyToByte(resp.GetValue()), &entry); err != nil {
+		return nil, time.Time{}, false, err
+	}
+	return entry.Data, entry.Updated, true, nil
+} // end func RedisRestApiCache.Load
+
+func (c RedisRestApiCache) Save(key string, data []byte) error {
+	entryBytes, err := JsonEncode(redisRestApiCacheEntry{Updated: TimeNow(), Data: data})
+	if err != nil {
+		return err
+	}
+	rx := RedisSetRequest{Key: c.Prefix + key, Expires: c.Expires}
+	rx.SetValue(string(entryBytes))
+	_, err = RedisSet(rx)
+	return err
+} // end func RedisRestApiCache.Save
+
 // GetResponseBodyBytes is used for reading the responses of outgoing requests from our service
 func GetResponseBodyBytes(r *http.Response) (bodyByte []byte, err error) {
 	defer r.Body.Close()
@@ -5476,9 +5612,9 @@ func RedisSet(rx RedisSetRequest) (resp RedisSetResponse, err error) {
 	// Handle the Redis Query & Result
 	//o("Key: %s, Value: %+v, Expires: %+v", rx.Key, rx.Value, rx.Expires)
 	if rx.Expires == 0 {
-		err = rdb.Set(rdbCtx, rx.Key, rx.Value, 0).Err()
+		err = rdb.Set(rdbCtx, rx.Key, rx.GetValue(), 0).Err()
 	} else {
-		err = rdb.Set(rdbCtx, rx.Key, rx.Value, TimeDuration(rx.Expires)).Err()
+		err = rdb.Set(rdbCtx, rx.Key, rx.GetValue(), TimeDuration(rx.Expires)).Err()
 	}
 	if err != nil {
 		resp.Success = false